@@ -0,0 +1,113 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/db-journey/migrate/v2/direction"
+	"github.com/db-journey/migrate/v2/file"
+)
+
+// WithAutoRollback makes operations roll back up migrations they applied,
+// in reverse order, when one of their migrations fails. Returned error is
+// *RollbackError then. Rollback isn't needed, so it's not done, in single
+// transaction mode.
+// If failed migration leaves its version dirty (see driver.DirtyTracker),
+// applied versions are still rolled back, failed one stays dirty.
+func WithAutoRollback() Option {
+	return func(h *Handle) error {
+		h.autoRollback = true
+		return nil
+	}
+}
+
+// errRollbackSkipped is the outcome of rollbacks not attempted
+// because previous one failed.
+var errRollbackSkipped = errors.New("skipped after previous rollback failed")
+
+// Rollback is the outcome of rolling back single version.
+type Rollback struct {
+	Version file.Version
+	// Err is nil if version was rolled back.
+	Err error
+}
+
+// RollbackError is returned when migration fails and migrations applied
+// before it were rolled back (see WithAutoRollback).
+type RollbackError struct {
+	// Err is the failure of migration.
+	Err error
+	// Rollbacks are outcomes of rolling back applied versions, newest first.
+	// Rollback stops at the first failure, remaining versions stay applied.
+	Rollbacks []Rollback
+}
+
+func (e *RollbackError) Error() string {
+	outcomes := make([]string, len(e.Rollbacks))
+	for i, r := range e.Rollbacks {
+		if r.Err == nil {
+			outcomes[i] = fmt.Sprintf("version %d rolled back", r.Version)
+		} else {
+			outcomes[i] = fmt.Sprintf("version %d not rolled back: %s", r.Version, r.Err)
+		}
+	}
+	return fmt.Sprintf("%s; %s", e.Err, strings.Join(outcomes, "; "))
+}
+
+// Unwrap returns the failure of migration.
+func (e *RollbackError) Unwrap() error {
+	return e.Err
+}
+
+// migrateFiles applies plan of migrations in order, rolling back up migrations
+// applied before failed one in auto rollback mode. Down files are taken from files.
+func (m *Handle) migrateFiles(ctx context.Context, files file.MigrationFiles, plan file.Files) error {
+	if err := m.checkPlaceholders(files, plan); err != nil {
+		return err
+	}
+	var applied file.Files
+	for _, f := range plan {
+		err := m.drvMigrate(ctx, f)
+		if err != nil {
+			if !m.autoRollback || m.dryRun != nil || m.tx(ctx) != nil || len(applied) == 0 {
+				return err
+			}
+			return m.rollback(ctx, files, applied, err)
+		}
+		if f.Direction == direction.Up {
+			applied = append(applied, f)
+		}
+	}
+	return nil
+}
+
+// rollback runs down migrations of applied files in reverse order.
+func (m *Handle) rollback(ctx context.Context, files file.MigrationFiles, applied file.Files, cause error) error {
+	rollbackErr := &RollbackError{Err: cause}
+	var failed bool
+	for i := len(applied) - 1; i >= 0; i-- {
+		r := Rollback{Version: applied[i].Version, Err: errRollbackSkipped}
+		if !failed {
+			r.Err = m.rollbackVersion(ctx, files, r.Version)
+			failed = r.Err != nil
+		}
+		rollbackErr.Rollbacks = append(rollbackErr.Rollbacks, r)
+	}
+	return rollbackErr
+}
+
+func (m *Handle) rollbackVersion(ctx context.Context, files file.MigrationFiles, version file.Version) error {
+	for _, f := range files {
+		if f.Version == version {
+			if err := f.CheckReversible(); err != nil {
+				return err
+			}
+			// operation refuses to start on dirty database, so dirty
+			// version can only be the failed migration of this operation
+			return m.apply(ctx, *f.DownFile)
+		}
+	}
+	return errors.New("no `down` migration file")
+}
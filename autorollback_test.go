@@ -0,0 +1,97 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/db-journey/migrate/v2/file"
+)
+
+func TestAutoRollback(t *testing.T) {
+	ctx := context.Background()
+	m, drv, cleanup := newMockHandle(t, map[string]string{
+		"001_first.up.sql":    "CREATE TABLE first;",
+		"001_first.down.sql":  "DROP TABLE first;",
+		"002_second.up.sql":   "CREATE TABLE second;",
+		"002_second.down.sql": "DROP TABLE second;",
+		"003_third.up.sql":    "CREATE TABLE third;",
+		"003_third.down.sql":  "DROP TABLE third;",
+		"004_fourth.up.sql":   "FAIL",
+	}, WithAutoRollback())
+	defer cleanup()
+
+	drv.versions = file.Versions{1}
+	err := m.Up(ctx)
+	var rollbackErr *RollbackError
+	if !errors.As(err, &rollbackErr) {
+		t.Fatalf("Expected rollback error, got %v", err)
+	}
+	expected := []Rollback{{Version: 3}, {Version: 2}}
+	if !reflect.DeepEqual(rollbackErr.Rollbacks, expected) {
+		t.Errorf("Expected rollbacks %+v, got %+v", expected, rollbackErr.Rollbacks)
+	}
+	if !strings.Contains(err.Error(), "migration failed; version 3 rolled back; version 2 rolled back") {
+		t.Errorf("Unexpected error message: %q", err)
+	}
+	if !reflect.DeepEqual(drv.versions, file.Versions{1}) {
+		t.Errorf("Expected only version applied before the call to stay, got %v", drv.versions)
+	}
+}
+
+func TestAutoRollbackFailure(t *testing.T) {
+	ctx := context.Background()
+	m, drv, cleanup := newMockHandle(t, map[string]string{
+		"001_first.up.sql":   "CREATE TABLE first;",
+		"001_first.down.sql": "DROP TABLE first;",
+		"002_second.up.sql":  "CREATE TABLE second;",
+		"003_third.up.sql":   "FAIL",
+	}, WithAutoRollback())
+	defer cleanup()
+
+	err := m.Up(ctx)
+	var rollbackErr *RollbackError
+	if !errors.As(err, &rollbackErr) {
+		t.Fatalf("Expected rollback error, got %v", err)
+	}
+	if len(rollbackErr.Rollbacks) != 2 || rollbackErr.Rollbacks[0].Err == nil || rollbackErr.Rollbacks[1].Err != errRollbackSkipped {
+		t.Errorf("Expected rollback of version 2 to fail and version 1 to be skipped, got %+v", rollbackErr.Rollbacks)
+	}
+	if !reflect.DeepEqual(drv.versions, file.Versions{2, 1}) {
+		t.Errorf("Expected versions to stay applied, got %v", drv.versions)
+	}
+}
+
+func TestAutoRollbackDirty(t *testing.T) {
+	ctx := context.Background()
+	m, drv, cleanup := newMockHandle(t, map[string]string{
+		"001_first.up.sql":    "CREATE TABLE first;",
+		"001_first.down.sql":  "DROP TABLE first;",
+		"002_second.up.sql":   "CREATE TABLE second;",
+		"002_second.down.sql": "DROP TABLE second;",
+		"003_third.up.sql":    "HALFWAY",
+		"003_third.down.sql":  "DROP TABLE third;",
+	}, WithAutoRollback())
+	defer cleanup()
+
+	err := m.Up(ctx)
+	var rollbackErr *RollbackError
+	if !errors.As(err, &rollbackErr) {
+		t.Fatalf("Expected rollback error, got %v", err)
+	}
+	expected := []Rollback{{Version: 2}, {Version: 1}}
+	if !reflect.DeepEqual(rollbackErr.Rollbacks, expected) {
+		t.Errorf("Expected rollbacks %+v, got %+v", expected, rollbackErr.Rollbacks)
+	}
+	if !reflect.DeepEqual(drv.versions, file.Versions{3}) {
+		t.Errorf("Expected only failed version to stay applied, got %v", drv.versions)
+	}
+	if !reflect.DeepEqual(drv.dirty, file.Versions{3}) {
+		t.Errorf("Expected failed version to stay dirty, got %v", drv.dirty)
+	}
+	if err := m.Up(ctx); err == nil || !strings.Contains(err.Error(), "dirty") {
+		t.Errorf("Expected dirty database to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/db-journey/migrate/v2/driver"
+	"github.com/db-journey/migrate/v2/file"
+)
+
+// Baseline marks all migrations up to and including given version as applied,
+// without running them. It's meant for adopting migrations on existing database,
+// which schema already corresponds to given version.
+// Driver must implement driver.VersionWriter.
+func (m *Handle) Baseline(ctx context.Context, version file.Version) error {
+	writer, ok := m.drv.(driver.VersionWriter)
+	if !ok {
+		return fmt.Errorf("driver %T can't record versions without running migrations", m.drv)
+	}
+	return m.locking(ctx, "Baseline", func(ctx context.Context) error {
+		files, versions, err := m.readFilesAndGetVersions(ctx)
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, f := range files {
+			if f.Version == version {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("no migration file for version %d", version)
+		}
+		for _, f := range files {
+			if f.Version > version || versions.Contains(f.Version) {
+				continue
+			}
+			if f.UpFile == nil {
+				return fmt.Errorf("no `up` migration file for version %d", f.Version)
+			}
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("interrupted before marking version %d as applied: %s", f.Version, ctx.Err())
+			default:
+			}
+			if m.dryRun != nil {
+				m.dryRunApply(f.Version, true)
+				continue
+			}
+			if err := writer.MarkApplied(*f.UpFile); err != nil {
+				return fmt.Errorf("failed to mark version %d as applied: %s", f.Version, err)
+			}
+		}
+		return nil
+	})
+}
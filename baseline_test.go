@@ -0,0 +1,39 @@
+package migrate
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/db-journey/migrate/v2/file"
+)
+
+func TestBaseline(t *testing.T) {
+	ctx := context.Background()
+	m, drv, cleanup := newMockHandle(t, map[string]string{
+		"001_first.up.sql":  "CREATE TABLE first;",
+		"002_second.up.sql": "CREATE TABLE second;",
+		"003_third.up.sql":  "CREATE TABLE third;",
+	})
+	defer cleanup()
+
+	if err := m.Baseline(ctx, 4); err == nil {
+		t.Error("Expected baseline at unknown version to fail")
+	}
+	if err := m.Baseline(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if len(drv.migrated) != 0 {
+		t.Errorf("Expected no migrations to run, got %v", drv.migrated)
+	}
+	if !reflect.DeepEqual(drv.versions, file.Versions{2, 1}) {
+		t.Errorf("Expected versions %v, got %v", file.Versions{2, 1}, drv.versions)
+	}
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(drv.migrated) != 1 || drv.migrated[0].Version != 3 {
+		t.Errorf("Expected only version 3 to be migrated, got %v", drv.migrated)
+	}
+}
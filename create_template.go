@@ -0,0 +1,84 @@
+package migrate
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"text/template"
+	"time"
+
+	"github.com/db-journey/migrate/v2/direction"
+	"github.com/db-journey/migrate/v2/driver"
+)
+
+// CreateTemplateData is passed to project templates of Create.
+type CreateTemplateData struct {
+	// Name of migration, as in file name.
+	Name string
+	// Version as in file name, e.g. zero padded by SequentialVersion.
+	Version string
+	// Author is the operator set by WithAppliedBy.
+	Author string
+	// Date is the time of creation, see WithClock.
+	Date time.Time
+}
+
+// WithCreateTemplates makes Create fill new migration files from project
+// templates in dir instead of driver's file template.
+// Templates are named after direction and driver's file extension,
+// e.g. up.sql and down.sql, and executed as Go templates with CreateTemplateData.
+// Templates named up.sql.tpl and down.sql.tpl produce .tpl migrations,
+// their placeholders are delimited by [[ and ]], so {{ and }} are left
+// to the migration template. Directions without template get
+// driver's file template.
+func WithCreateTemplates(dir string) Option {
+	return func(h *Handle) error {
+		if dir == "" {
+			return errors.New("create templates directory can't be empty")
+		}
+		h.createTemplates = dir
+		return nil
+	}
+}
+
+// createContent renders content of new migration file in direction d,
+// telling if the file is a template itself.
+func (m *Handle) createContent(d direction.Direction, data CreateTemplateData) (content []byte, isTemplate bool, err error) {
+	if m.createTemplates == "" {
+		return driver.FileTemplate(m.drv), false, nil
+	}
+	if _, err := os.Stat(m.createTemplates); err != nil {
+		return nil, false, err
+	}
+
+	name := filepath.Join(m.createTemplates, d.String()+"."+driver.FileExtension(m.drv))
+	raw, err := ioutil.ReadFile(name + ".tpl")
+	switch {
+	case err == nil:
+		name, isTemplate = name+".tpl", true
+	case os.IsNotExist(err):
+		if raw, err = ioutil.ReadFile(name); os.IsNotExist(err) {
+			return driver.FileTemplate(m.drv), false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+	default:
+		return nil, false, err
+	}
+
+	tmpl := template.New(filepath.Base(name)).Option("missingkey=error")
+	if isTemplate {
+		tmpl = tmpl.Delims("[[", "]]")
+	}
+	if tmpl, err = tmpl.Parse(string(raw)); err != nil {
+		return nil, false, err
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return nil, false, err
+	}
+	return b.Bytes(), isTemplate, nil
+}
@@ -0,0 +1,70 @@
+package migrate
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp", "migrate-templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	templates := map[string]string{
+		"up.sql":       "-- {{ .Version }} {{ .Name }} by {{ .Author }} on {{ .Date.Format \"2006-01-02\" }}\n",
+		"down.sql.tpl": "-- [[ .Name ]]\nDROP TABLE {{ .SCHEMA }}.t;\n",
+		"up.cql":       "unused",
+	}
+	for name, content := range templates {
+		if err := ioutil.WriteFile(path.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	m, _, cleanup := newMockHandle(t, nil,
+		WithCreateTemplates(dir),
+		WithAppliedBy("alice"),
+		WithClock(func() time.Time { return now }),
+		WithVersionGenerator(SequentialVersion(3)))
+	defer cleanup()
+
+	mf, err := m.Create("add table")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mf.UpFile.FileName != "001_add_table.up.sql" || mf.DownFile.FileName != "001_add_table.down.sql.tpl" {
+		t.Errorf("unexpected file names %s, %s", mf.UpFile.FileName, mf.DownFile.FileName)
+	}
+	up, err := ioutil.ReadFile(path.Join(m.migrationsPath, mf.UpFile.FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "-- 001 add_table by alice on 2024-03-01\n"; string(up) != expected {
+		t.Errorf("expected up file %q, got %q", expected, up)
+	}
+	down, err := ioutil.ReadFile(path.Join(m.migrationsPath, mf.DownFile.FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "-- add_table\nDROP TABLE {{ .SCHEMA }}.t;\n"; string(down) != expected {
+		t.Errorf("expected down file %q, got %q", expected, down)
+	}
+
+	if err := ioutil.WriteFile(path.Join(dir, "up.sql"), []byte("{{ .Nope }}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Create("broken"); err == nil || !strings.Contains(err.Error(), "up.sql") {
+		t.Errorf("expected error naming the template, got %v", err)
+	}
+
+	m.createTemplates = path.Join(dir, "missing")
+	if _, err := m.Create("missing"); err == nil {
+		t.Error("expected error for missing templates directory")
+	}
+}
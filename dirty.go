@@ -0,0 +1,47 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/db-journey/migrate/v2/driver"
+	"github.com/db-journey/migrate/v2/file"
+)
+
+// Force clears dirty mark of the version, which migration failed halfway,
+// after the operator fixed database schema manually. Version stays recorded
+// as applied. Driver must implement driver.DirtyTracker.
+func (m *Handle) Force(ctx context.Context, version file.Version) error {
+	if m.dryRun != nil {
+		return errors.New("can't force version in dry run mode")
+	}
+	tracker, ok := m.drv.(driver.DirtyTracker)
+	if !ok {
+		return fmt.Errorf("driver %T doesn't track dirty versions", m.drv)
+	}
+	return m.locking(ctx, "Force", func(ctx context.Context) error {
+		dirty, err := tracker.Dirty()
+		if err != nil {
+			return err
+		}
+		for _, v := range dirty {
+			if v == version {
+				return tracker.Force(version)
+			}
+		}
+		return fmt.Errorf("version %d is not dirty", version)
+	})
+}
+
+// checkDirty refuses to migrate while database has dirty versions.
+func (m *Handle) checkDirty() error {
+	dirty, err := driver.Dirty(m.drv)
+	if err != nil {
+		return err
+	}
+	if len(dirty) > 0 {
+		return fmt.Errorf("database is dirty at version(s) %v: migration failed halfway, fix it manually and call Force", dirty)
+	}
+	return nil
+}
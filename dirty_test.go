@@ -0,0 +1,46 @@
+package migrate
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/db-journey/migrate/v2/file"
+)
+
+func TestDirty(t *testing.T) {
+	ctx := context.Background()
+	m, drv, cleanup := newMockHandle(t, map[string]string{
+		"001_first.up.sql":  "CREATE TABLE first;",
+		"002_second.up.sql": "HALFWAY",
+		"003_third.up.sql":  "CREATE TABLE third;",
+	})
+	defer cleanup()
+
+	if err := m.Up(ctx); err == nil {
+		t.Fatal("Expected migration to fail")
+	}
+	if !reflect.DeepEqual(drv.dirty, file.Versions{2}) {
+		t.Fatalf("Expected version 2 to be dirty, got %v", drv.dirty)
+	}
+
+	err := m.Up(ctx)
+	if err == nil || !strings.Contains(err.Error(), "dirty") {
+		t.Fatalf("Expected migrations to be refused while version is dirty, got %v", err)
+	}
+
+	if err := m.Force(ctx, 1); err == nil {
+		t.Error("Expected Force of clean version to fail")
+	}
+	if err := m.Force(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	expected := file.Versions{3, 2, 1}
+	if !reflect.DeepEqual(drv.versions, expected) {
+		t.Errorf("Expected versions %v, got %v", expected, drv.versions)
+	}
+}
@@ -2,9 +2,11 @@
 package driver
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"regexp"
+	"time"
 
 	"github.com/db-journey/migrate/v2/file"
 )
@@ -31,6 +33,156 @@ type Driver interface {
 	Execute(statement string) error
 }
 
+// ContextDriver represents driver that passes context to the database,
+// so cancellation and deadlines interrupt running statements.
+type ContextDriver interface {
+	// MigrateContext is Migrate, interrupted when ctx is done.
+	MigrateContext(ctx context.Context, file file.File) error
+
+	// VersionsContext is Versions, interrupted when ctx is done.
+	VersionsContext(ctx context.Context) (file.Versions, error)
+
+	// ExecuteContext is Execute, interrupted when ctx is done.
+	ExecuteContext(ctx context.Context, statement string) error
+}
+
+// MigrateContext calls MigrateContext method if driver implements ContextDriver,
+// Migrate otherwise.
+func MigrateContext(ctx context.Context, d Driver, f file.File) error {
+	if d, ok := d.(ContextDriver); ok {
+		return d.MigrateContext(ctx, f)
+	}
+	return d.Migrate(f)
+}
+
+// VersionsContext calls VersionsContext method if driver implements ContextDriver,
+// Versions otherwise.
+func VersionsContext(ctx context.Context, d Driver) (file.Versions, error) {
+	if d, ok := d.(ContextDriver); ok {
+		return d.VersionsContext(ctx)
+	}
+	return d.Versions()
+}
+
+// ExecuteContext calls ExecuteContext method if driver implements ContextDriver,
+// Execute otherwise.
+func ExecuteContext(ctx context.Context, d Driver, statement string) error {
+	if d, ok := d.(ContextDriver); ok {
+		return d.ExecuteContext(ctx, statement)
+	}
+	return d.Execute(statement)
+}
+
+// Transactional represents driver that can run several migrations
+// in single transaction, which commits or rolls back as a unit.
+type Transactional interface {
+	// BeginTx starts transaction for migrations.
+	BeginTx(ctx context.Context) (Tx, error)
+}
+
+// Tx is a transaction started by Transactional driver.
+type Tx interface {
+	// MigrateContext applies migration and updates version table within transaction.
+	MigrateContext(ctx context.Context, file file.File) error
+
+	// VersionsContext returns applied versions as seen within transaction.
+	VersionsContext(ctx context.Context) (file.Versions, error)
+
+	Commit() error
+	Rollback() error
+}
+
+// Record is a row of the version table.
+// Fields other than Version are empty for versions applied before
+// the driver started to record them.
+type Record struct {
+	Version file.Version
+
+	// Name of the migration.
+	Name string
+
+	// Checksum of the rendered up migration file (see file.File.Checksum).
+	Checksum string
+
+	// AppliedAt is the time migration started to apply.
+	AppliedAt time.Time
+
+	// Duration of migration execution.
+	Duration time.Duration
+
+	// AppliedBy identifies who applied the migration (see Operator).
+	AppliedBy string
+
+	// Dirty is set if migration was executed without transaction
+	// and didn't complete (see DirtyTracker).
+	Dirty bool
+}
+
+// Recorder represents driver that keeps records of applied migrations
+// in addition to their versions.
+type Recorder interface {
+	// Records returns rows of the version table, newest version first.
+	Records() ([]Record, error)
+}
+
+// VersionWriter represents driver that can record migration as applied
+// without running it, e.g. to baseline existing database.
+type VersionWriter interface {
+	// MarkApplied records version of given up migration file as applied.
+	MarkApplied(f file.File) error
+}
+
+// DirtyTracker represents driver that marks version as dirty before
+// executing its migration without transaction, and clears the mark
+// once migration succeeds. Dirty version means database schema may be
+// left half-migrated and needs manual fix.
+type DirtyTracker interface {
+	// Dirty returns dirty versions.
+	Dirty() (file.Versions, error)
+
+	// Force clears dirty mark of given version, leaving it recorded as applied.
+	Force(version file.Version) error
+}
+
+// Operator represents driver that records who applies migrations.
+type Operator interface {
+	// SetAppliedBy sets value recorded as Record.AppliedBy
+	// for subsequently applied migrations.
+	SetAppliedBy(appliedBy string)
+}
+
+// SetAppliedBy calls SetAppliedBy method if driver implements Operator
+func SetAppliedBy(d Driver, appliedBy string) {
+	if d, ok := d.(Operator); ok {
+		d.SetAppliedBy(appliedBy)
+	}
+}
+
+// Quoter represents driver that can quote identifiers and literals
+// in its language, e.g. for quoteIdent and quoteLiteral template functions.
+type Quoter interface {
+	QuoteIdentifier(name string) string
+	QuoteLiteral(value string) string
+}
+
+// QuoteIdentifier calls QuoteIdentifier method if driver implements Quoter,
+// failing otherwise.
+func QuoteIdentifier(d Driver, name string) (string, error) {
+	if q, ok := d.(Quoter); ok {
+		return q.QuoteIdentifier(name), nil
+	}
+	return "", fmt.Errorf("driver %T can't quote identifiers", d)
+}
+
+// QuoteLiteral calls QuoteLiteral method if driver implements Quoter,
+// failing otherwise.
+func QuoteLiteral(d Driver, value string) (string, error) {
+	if q, ok := d.(Quoter); ok {
+		return q.QuoteLiteral(value), nil
+	}
+	return "", fmt.Errorf("driver %T can't quote literals", d)
+}
+
 // Lockable represents driver that supports database locking.
 // Implement if possible to make it safe to run migrations concurrently.
 // NOTE: Probably better to move into Driver interface to make sure it's not
@@ -97,3 +249,11 @@ func getScheme(url string) string {
 	}
 	return match[1]
 }
+
+// Dirty returns dirty versions if driver implements DirtyTracker interface.
+func Dirty(d Driver) (file.Versions, error) {
+	if d, ok := d.(DirtyTracker); ok {
+		return d.Dirty()
+	}
+	return nil, nil
+}
@@ -1,7 +1,20 @@
 package bash
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/db-journey/migrate/v2/driver"
+)
 
 func TestMigrate(t *testing.T) {
 
 }
+
+// TestBaseline checks that baseline isn't silently ignored,
+// as bash driver doesn't keep track of versions.
+func TestBaseline(t *testing.T) {
+	var d driver.Driver = &Driver{}
+	if _, ok := d.(driver.VersionWriter); ok {
+		t.Error("Expected bash driver not to record versions without running migrations")
+	}
+}
@@ -2,6 +2,7 @@
 package cassandra
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"sort"
@@ -16,7 +17,9 @@ import (
 )
 
 type Driver struct {
-	session *gocql.Session
+	session   *gocql.Session
+	keyspace  string
+	appliedBy string
 }
 
 // make sure our driver still implements the driver.Driver interface
@@ -85,6 +88,7 @@ func Open(rawurl string) (driver.Driver, error) {
 		}
 	}
 
+	driver.keyspace = cluster.Keyspace
 	driver.session, err = cluster.CreateSession()
 	if err != nil {
 		return nil, err
@@ -104,29 +108,62 @@ func (driver *Driver) Close() error {
 
 func (driver *Driver) ensureVersionTableExists() error {
 	err := driver.session.Query("CREATE TABLE IF NOT EXISTS " + tableName + " (version bigint primary key);").Exec()
+	if err != nil {
+		return err
+	}
+	for _, column := range []struct{ name, cqlType string }{
+		{"name", "text"},
+		{"checksum", "text"},
+		{"applied_at", "timestamp"},
+		{"duration_ms", "bigint"},
+		{"applied_by", "text"},
+		{"dirty", "boolean"},
+	} {
+		if err := driver.ensureColumnExists(column.name, column.cqlType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureColumnExists adds column to the version table, unless it already exists.
+// Existing column is detected by the error of ALTER TABLE, as system tables
+// describing columns differ between Cassandra 2.x and 3.x.
+func (driver *Driver) ensureColumnExists(column, cqlType string) error {
+	err := driver.session.Query("ALTER TABLE " + tableName + " ADD " + column + " " + cqlType).Exec()
+	if err != nil && isExistingColumnErr(err) {
+		return nil
+	}
 	return err
 }
 
-func (driver *Driver) Migrate(f file.File) (err error) {
-	defer func() {
-		if err != nil {
-			// Invert version direction if we couldn't apply the changes for some reason.
-			if errRollback := driver.session.Query("DELETE FROM "+tableName+" WHERE version = ?", f.Version).Exec(); errRollback != nil {
-				err = fmt.Errorf("%s; failed to rollback version: %s", err, errRollback)
-			}
-		}
-	}()
+// isExistingColumnErr tells if ALTER TABLE ADD failed because column exists:
+// "conflicts with an existing column" up to 3.x, "already exists" since 4.0.
+func isExistingColumnErr(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "conflicts with an existing column") || strings.Contains(msg, "already exists")
+}
 
+// Migrate runs migration. Cassandra has no transactions, so version is
+// marked dirty before executing statements and cleared once all of them succeed.
+func (driver *Driver) Migrate(f file.File) error {
+	return driver.MigrateContext(context.Background(), f)
+}
+
+// MigrateContext runs migration, interrupting it when ctx is done.
+func (driver *Driver) MigrateContext(ctx context.Context, f file.File) (err error) {
 	if err = f.ReadContent(); err != nil {
 		return
 	}
 
+	start := time.Now()
 	if f.Direction == direction.Up {
-		if err = driver.session.Query("INSERT INTO "+tableName+" (version) VALUES (?)", f.Version).Exec(); err != nil {
+		if err = driver.session.Query("INSERT INTO "+tableName+" (version, name, checksum, applied_at, applied_by, dirty) VALUES (?, ?, ?, ?, ?, true)",
+			f.Version, f.Name, f.Checksum(), start, driver.appliedBy).WithContext(ctx).Exec(); err != nil {
 			return
 		}
 	} else if f.Direction == direction.Down {
-		if err = driver.session.Query("DELETE FROM "+tableName+" WHERE version = ?", f.Version).Exec(); err != nil {
+		if err = driver.session.Query("UPDATE "+tableName+" SET dirty = true WHERE version = ?", f.Version).WithContext(ctx).Exec(); err != nil {
 			return
 		}
 	}
@@ -137,13 +174,65 @@ func (driver *Driver) Migrate(f file.File) (err error) {
 			continue
 		}
 
-		if err = driver.session.Query(query).Exec(); err != nil {
-			return
+		if err = driver.session.Query(query).WithContext(ctx).Exec(); err != nil {
+			return fmt.Errorf("%s (version %d is marked as dirty)", err, f.Version)
 		}
 	}
+
+	// migration is applied at this point, so recording it isn't interrupted by ctx
+	if f.Direction == direction.Up {
+		err = driver.session.Query("UPDATE "+tableName+" SET dirty = false, duration_ms = ? WHERE version = ?", time.Since(start).Milliseconds(), f.Version).Exec()
+	} else if f.Direction == direction.Down {
+		err = driver.session.Query("DELETE FROM "+tableName+" WHERE version = ?", f.Version).Exec()
+	}
 	return
 }
 
+// MarkApplied records version of given up migration file without running it.
+func (driver *Driver) MarkApplied(f file.File) error {
+	if err := f.ReadContent(); err != nil {
+		return err
+	}
+	return driver.session.Query("INSERT INTO "+tableName+" (version, name, checksum, applied_at, applied_by, dirty) VALUES (?, ?, ?, ?, ?, false)",
+		f.Version, f.Name, f.Checksum(), time.Now(), driver.appliedBy).Exec()
+}
+
+// Dirty returns versions whose migrations didn't complete.
+func (driver *Driver) Dirty() (file.Versions, error) {
+	versions := file.Versions{}
+	iter := driver.session.Query("SELECT version, dirty FROM " + tableName).Iter()
+	var version int64
+	var dirty bool
+	for iter.Scan(&version, &dirty) {
+		if dirty {
+			versions = append(versions, file.Version(version))
+		}
+	}
+	err := iter.Close()
+	sort.Sort(sort.Reverse(versions))
+	return versions, err
+}
+
+// Force clears dirty mark of given version.
+func (driver *Driver) Force(version file.Version) error {
+	return driver.session.Query("UPDATE "+tableName+" SET dirty = false WHERE version = ?", version).Exec()
+}
+
+// SetAppliedBy sets value recorded in applied_by column.
+func (driver *Driver) SetAppliedBy(appliedBy string) {
+	driver.appliedBy = appliedBy
+}
+
+// QuoteIdentifier quotes identifier for use in CQL, e.g. "MyTable".
+func (driver *Driver) QuoteIdentifier(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// QuoteLiteral quotes string literal for use in CQL.
+func (driver *Driver) QuoteLiteral(value string) string {
+	return `'` + strings.Replace(value, `'`, `''`, -1) + `'`
+}
+
 // Version returns the current migration version.
 func (driver *Driver) Version() (file.Version, error) {
 	versions, err := driver.Versions()
@@ -155,8 +244,13 @@ func (driver *Driver) Version() (file.Version, error) {
 
 // Versions returns the list of applied migrations.
 func (driver *Driver) Versions() (file.Versions, error) {
+	return driver.VersionsContext(context.Background())
+}
+
+// VersionsContext returns the list of applied migrations.
+func (driver *Driver) VersionsContext(ctx context.Context) (file.Versions, error) {
 	versions := file.Versions{}
-	iter := driver.session.Query("SELECT version FROM " + tableName).Iter()
+	iter := driver.session.Query("SELECT version FROM " + tableName).WithContext(ctx).Iter()
 	var version int64
 	for iter.Scan(&version) {
 		versions = append(versions, file.Version(version))
@@ -166,9 +260,40 @@ func (driver *Driver) Versions() (file.Versions, error) {
 	return versions, err
 }
 
+// Records returns rows of the version table, newest version first.
+func (drv *Driver) Records() ([]driver.Record, error) {
+	records := []driver.Record{}
+	iter := drv.session.Query("SELECT version, name, checksum, applied_at, duration_ms, applied_by, dirty FROM " + tableName).Iter()
+	var version, durationMs int64
+	var name, checksum, appliedBy string
+	var appliedAt time.Time
+	var dirty bool
+	for iter.Scan(&version, &name, &checksum, &appliedAt, &durationMs, &appliedBy, &dirty) {
+		records = append(records, driver.Record{
+			Version:   file.Version(version),
+			Name:      name,
+			Checksum:  checksum,
+			AppliedAt: appliedAt,
+			Duration:  time.Duration(durationMs) * time.Millisecond,
+			AppliedBy: appliedBy,
+			Dirty:     dirty,
+		})
+	}
+	err := iter.Close()
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Version > records[j].Version
+	})
+	return records, err
+}
+
 // Execute a SQL statement
 func (driver *Driver) Execute(statement string) error {
-	return driver.session.Query(statement).Exec()
+	return driver.ExecuteContext(context.Background(), statement)
+}
+
+// ExecuteContext executes a SQL statement, interrupting it when ctx is done.
+func (driver *Driver) ExecuteContext(ctx context.Context, statement string) error {
+	return driver.session.Query(statement).WithContext(ctx).Exec()
 }
 
 func init() {
@@ -125,8 +125,8 @@ func TestMigrate(t *testing.T) {
 		t.Error("Expected test case to fail")
 	}
 
-	// Check versions applied in DB.
-	expectedVersions = file.Versions{}
+	// Failed migration stays recorded as dirty.
+	expectedVersions = file.Versions{files[2].Version}
 	versions, err = d.Versions()
 	if err != nil {
 		t.Errorf("Could not fetch versions: %s", err)
@@ -136,6 +136,14 @@ func TestMigrate(t *testing.T) {
 		t.Errorf("Expected versions to be: %v, got: %v", expectedVersions, versions)
 	}
 
+	dirty, err := d.(*Driver).Dirty()
+	if err != nil {
+		t.Errorf("Could not fetch dirty versions: %s", err)
+	}
+	if !reflect.DeepEqual(dirty, expectedVersions) {
+		t.Errorf("Expected dirty versions to be: %v, got: %v", expectedVersions, dirty)
+	}
+
 	if err := resetKeySpace(session); err != nil {
 		t.Fatal(err)
 	}
@@ -2,9 +2,11 @@
 package crate
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/db-journey/migrate/v2/direction"
 	"github.com/db-journey/migrate/v2/driver"
@@ -17,7 +19,8 @@ func init() {
 }
 
 type Driver struct {
-	db *sql.DB
+	db        *sql.DB
+	appliedBy string
 }
 
 const tableName = "schema_migrations"
@@ -64,9 +67,14 @@ func (driver *Driver) Version() (file.Version, error) {
 
 // Versions returns the list of applied migrations.
 func (driver *Driver) Versions() (file.Versions, error) {
+	return driver.VersionsContext(context.Background())
+}
+
+// VersionsContext returns the list of applied migrations.
+func (driver *Driver) VersionsContext(ctx context.Context) (file.Versions, error) {
 	versions := file.Versions{}
 
-	rows, err := driver.db.Query("SELECT version FROM " + tableName + " ORDER BY version DESC")
+	rows, err := driver.db.QueryContext(ctx, "SELECT version FROM "+tableName+" ORDER BY version DESC")
 	if err != nil {
 		return versions, err
 	}
@@ -83,34 +91,147 @@ func (driver *Driver) Versions() (file.Versions, error) {
 	return versions, err
 }
 
+// Records returns rows of the version table, newest version first.
+func (drv *Driver) Records() ([]driver.Record, error) {
+	records := []driver.Record{}
+
+	rows, err := drv.db.Query("SELECT version, name, checksum, applied_at, duration_ms, applied_by, dirty FROM " + tableName + " ORDER BY version DESC")
+	if err != nil {
+		return records, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var record driver.Record
+		var name, checksum, appliedBy sql.NullString
+		// crate returns timestamps as epoch milliseconds,
+		// which may come as float numbers from the JSON API
+		var appliedAt, durationMs sql.NullFloat64
+		var dirty sql.NullBool
+		err := rows.Scan(&record.Version, &name, &checksum, &appliedAt, &durationMs, &appliedBy, &dirty)
+		if err != nil {
+			return records, err
+		}
+		record.Name = name.String
+		record.Checksum = checksum.String
+		if appliedAt.Valid {
+			record.AppliedAt = time.Unix(0, int64(appliedAt.Float64)*int64(time.Millisecond))
+		}
+		record.Duration = time.Duration(durationMs.Float64) * time.Millisecond
+		record.AppliedBy = appliedBy.String
+		record.Dirty = dirty.Bool
+		records = append(records, record)
+	}
+	err = rows.Err()
+	return records, err
+}
+
+// Migrate runs migration. Crate has no transactions, so version is marked
+// dirty before executing statements and cleared once all of them succeed.
 func (driver *Driver) Migrate(f file.File) error {
+	return driver.MigrateContext(context.Background(), f)
+}
+
+// MigrateContext runs migration, interrupting it when ctx is done.
+func (driver *Driver) MigrateContext(ctx context.Context, f file.File) error {
 	if err := f.ReadContent(); err != nil {
 		return err
 	}
 
+	start := time.Now()
+	if f.Direction == direction.Up {
+		// timestamps are passed as epoch milliseconds, which is what crate stores
+		if _, err := driver.db.ExecContext(ctx, "INSERT INTO "+tableName+" (version, name, checksum, applied_at, applied_by, dirty) VALUES (?, ?, ?, ?, ?, true)",
+			f.Version, f.Name, f.Checksum(), start.UnixNano()/int64(time.Millisecond), driver.appliedBy); err != nil {
+			return err
+		}
+	} else if f.Direction == direction.Down {
+		if _, err := driver.db.ExecContext(ctx, "UPDATE "+tableName+" SET dirty = true WHERE version=?", f.Version); err != nil {
+			return err
+		}
+	}
+
 	lines := splitContent(string(f.Content))
 	for _, line := range lines {
-		_, err := driver.db.Exec(line)
+		_, err := driver.db.ExecContext(ctx, line)
 		if err != nil {
-			return err
+			return fmt.Errorf("%s (version %d is marked as dirty)", err, f.Version)
 		}
 	}
 
+	// migration is applied at this point, so recording it isn't interrupted by ctx
 	if f.Direction == direction.Up {
-		if _, err := driver.db.Exec("INSERT INTO "+tableName+" (version) VALUES (?)", f.Version); err != nil {
+		if _, err := driver.db.ExecContext(context.Background(), "UPDATE "+tableName+" SET dirty = false, duration_ms = ? WHERE version=?", time.Since(start).Milliseconds(), f.Version); err != nil {
 			return err
 		}
 	} else if f.Direction == direction.Down {
-		if _, err := driver.db.Exec("DELETE FROM "+tableName+" WHERE version=?", f.Version); err != nil {
+		if _, err := driver.db.ExecContext(context.Background(), "DELETE FROM "+tableName+" WHERE version=?", f.Version); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// MarkApplied records version of given up migration file without running it.
+func (driver *Driver) MarkApplied(f file.File) error {
+	if err := f.ReadContent(); err != nil {
+		return err
+	}
+	_, err := driver.db.Exec("INSERT INTO "+tableName+" (version, name, checksum, applied_at, applied_by, dirty) VALUES (?, ?, ?, ?, ?, false)",
+		f.Version, f.Name, f.Checksum(), time.Now().UnixNano()/int64(time.Millisecond), driver.appliedBy)
+	return err
+}
+
+// Dirty returns versions whose migrations didn't complete.
+func (driver *Driver) Dirty() (file.Versions, error) {
+	// make recent writes visible to the query below
+	if _, err := driver.db.Exec("REFRESH TABLE " + tableName); err != nil {
+		return nil, err
+	}
+	rows, err := driver.db.Query("SELECT version FROM " + tableName + " WHERE dirty = true ORDER BY version DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	versions := file.Versions{}
+	for rows.Next() {
+		var version file.Version
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+// Force clears dirty mark of given version.
+func (driver *Driver) Force(version file.Version) error {
+	_, err := driver.db.Exec("UPDATE "+tableName+" SET dirty = false WHERE version=?", version)
+	return err
+}
+
+// SetAppliedBy sets value recorded in applied_by column.
+func (driver *Driver) SetAppliedBy(appliedBy string) {
+	driver.appliedBy = appliedBy
+}
+
+// QuoteIdentifier quotes identifier for use in SQL, e.g. "my table".
+func (driver *Driver) QuoteIdentifier(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// QuoteLiteral quotes string literal for use in SQL.
+func (driver *Driver) QuoteLiteral(value string) string {
+	return `'` + strings.Replace(value, `'`, `''`, -1) + `'`
+}
+
 // Execute a statement
 func (driver *Driver) Execute(statement string) error {
-	_, err := driver.db.Exec(statement)
+	return driver.ExecuteContext(context.Background(), statement)
+}
+
+// ExecuteContext executes a statement, interrupting it when ctx is done.
+func (driver *Driver) ExecuteContext(ctx context.Context, statement string) error {
+	_, err := driver.db.ExecContext(ctx, statement)
 	return err
 }
 
@@ -131,5 +252,31 @@ func (driver *Driver) ensureVersionTableExists() error {
 	if _, err := driver.db.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version LONG PRIMARY KEY)", tableName)); err != nil {
 		return err
 	}
+	for _, column := range []struct{ name, definition string }{
+		{"name", "STRING"},
+		{"checksum", "STRING"},
+		{"applied_at", "TIMESTAMP"},
+		{"duration_ms", "LONG"},
+		{"applied_by", "STRING"},
+		{"dirty", "BOOLEAN"},
+	} {
+		if err := driver.ensureColumnExists(column.name, column.definition); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// ensureColumnExists adds column to the version table, unless it already exists.
+// Version table is created in the default doc schema.
+func (driver *Driver) ensureColumnExists(column, definition string) error {
+	var c int
+	if err := driver.db.QueryRow("SELECT count(*) FROM information_schema.columns WHERE table_schema = 'doc' AND table_name = ? AND column_name = ?", tableName, column).Scan(&c); err != nil {
+		return err
+	}
+	if c > 0 {
+		return nil
+	}
+	_, err := driver.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", tableName, column, definition))
+	return err
+}
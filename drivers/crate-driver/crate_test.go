@@ -101,6 +101,50 @@ func TestMigrate(t *testing.T) {
 		}
 	}
 
+	// failed migration leaves its version dirty until it's forced
+	halfway := file.File{
+		Path:      "/foobar",
+		FileName:  "20161122193105_foobar.up.sql",
+		Version:   20161122193105,
+		Name:      "foobar",
+		Direction: direction.Up,
+		Content: []byte(`
+                CREATE TABLE halfway (id integer primary key);
+                CREATE TABLE error (id THIS WILL CAUSE AN ERROR);
+            `),
+	}
+	if err := driver.Migrate(halfway); err == nil {
+		t.Fatal("Migration should have failed but succeeded")
+	}
+	dirty, err := driver.(*Driver).Dirty()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirty) != 1 || dirty[0] != halfway.Version {
+		t.Errorf("Expected version %d to be dirty, got %v", halfway.Version, dirty)
+	}
+	if err := driver.(*Driver).Force(halfway.Version); err != nil {
+		t.Fatal(err)
+	}
+	if dirty, err = driver.(*Driver).Dirty(); err != nil || len(dirty) != 0 {
+		t.Errorf("Expected no dirty versions after Force, got %v, %v", dirty, err)
+	}
+	versions, err := driver.Versions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !versions.Contains(halfway.Version) {
+		t.Errorf("Expected forced version %d to stay applied, got %v", halfway.Version, versions)
+	}
+	for _, stmt := range []string{
+		"DROP TABLE halfway",
+		fmt.Sprintf("DELETE FROM %s WHERE version = %d", tableName, halfway.Version),
+	} {
+		if err := driver.Execute(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
 	if err := driver.Close(); err != nil {
 		t.Fatal(err)
 	}
@@ -12,6 +12,7 @@ import (
 	"io/ioutil"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/db-journey/migrate/v2/direction"
 	"github.com/db-journey/migrate/v2/driver"
@@ -47,6 +48,7 @@ func init() {
 type Driver struct {
 	db          *sql.DB
 	versionConn *sql.Conn
+	appliedBy   string
 }
 
 // Open driver
@@ -110,13 +112,23 @@ func (drv *Driver) Close() error {
 
 // Execute sql
 func (drv *Driver) Execute(sql string) error {
-	_, err := drv.db.Exec(sql)
+	return drv.ExecuteContext(context.Background(), sql)
+}
+
+// ExecuteContext executes sql, cancelling it when ctx is done.
+func (drv *Driver) ExecuteContext(ctx context.Context, sql string) error {
+	_, err := drv.db.ExecContext(ctx, sql)
 	return err
 }
 
 // Migrate runs migration.
 // It locks schema_migrations table, so concurrent execution is safe.
 func (drv *Driver) Migrate(f file.File) error {
+	return drv.MigrateContext(context.Background(), f)
+}
+
+// MigrateContext runs migration, cancelling running statement when ctx is done.
+func (drv *Driver) MigrateContext(ctx context.Context, f file.File) error {
 	if drv.versionConn == nil {
 		return errors.New("migrate must call Lock before Migrate")
 	}
@@ -129,21 +141,58 @@ func (drv *Driver) Migrate(f file.File) error {
 		return fmt.Errorf("failed to parse migration: %s", err)
 	}
 
-	err = migration.exec(drv.db)
+	start := time.Now()
+	if migration.noTx {
+		// migration can't be rolled back if it fails halfway, mark it dirty until it succeeds
+		if f.Direction == direction.Down {
+			_, err = drv.versionConn.ExecContext(ctx, "UPDATE "+versionsTableName+" SET dirty = true WHERE version = ?", f.Version)
+		} else {
+			_, err = drv.versionConn.ExecContext(ctx, "INSERT INTO "+versionsTableName+" (version, name, checksum, applied_at, applied_by, dirty) VALUES (?, ?, ?, ?, ?, true)",
+				f.Version, f.Name, f.Checksum(), start.UTC(), drv.appliedBy)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to mark migration %d as dirty: %s", f.Version, err)
+		}
+	}
+
+	err = migration.exec(ctx, drv.db)
 	if err != nil {
+		if migration.noTx {
+			err = fmt.Errorf("%s (version %d is marked as dirty)", err, f.Version)
+		}
 		return err
 	}
 
-	versionUpdSQL := "INSERT INTO " + versionsTableName + " (version) VALUES (?)"
-	if f.Direction == direction.Down {
-		versionUpdSQL = "DELETE FROM " + versionsTableName + " WHERE version = ?"
+	// migration is applied at this point, so recording it isn't interrupted by ctx
+	switch {
+	case f.Direction == direction.Down:
+		_, err = drv.versionConn.ExecContext(context.Background(), "DELETE FROM "+versionsTableName+" WHERE version = ?", f.Version)
+	case migration.noTx:
+		_, err = drv.versionConn.ExecContext(context.Background(), "UPDATE "+versionsTableName+" SET dirty = false, duration_ms = ? WHERE version = ?",
+			time.Since(start).Milliseconds(), f.Version)
+	default:
+		_, err = drv.versionConn.ExecContext(context.Background(), "INSERT INTO "+versionsTableName+" (version, name, checksum, applied_at, duration_ms, applied_by) VALUES (?, ?, ?, ?, ?, ?)",
+			f.Version, f.Name, f.Checksum(), start.UTC(), time.Since(start).Milliseconds(), drv.appliedBy)
 	}
-	if _, err = drv.versionConn.ExecContext(context.TODO(), versionUpdSQL, f.Version); err != nil {
+	if err != nil {
 		err = fmt.Errorf("migration %d was successfully applied, but failed to update schema_migrations table: %s", f.Version, err)
 	}
 	return err
 }
 
+// MarkApplied records version of given up migration file without running it.
+func (drv *Driver) MarkApplied(f file.File) error {
+	if err := f.ReadContent(); err != nil {
+		return err
+	}
+	if err := drv.initVersionConn(); err != nil {
+		return err
+	}
+	_, err := drv.versionConn.ExecContext(context.TODO(), "INSERT INTO "+versionsTableName+" (version, name, checksum, applied_at, applied_by) VALUES (?, ?, ?, ?, ?)",
+		f.Version, f.Name, f.Checksum(), time.Now().UTC(), drv.appliedBy)
+	return err
+}
+
 // Version returns the current migration version.
 func (drv *Driver) Version() (file.Version, error) {
 	var version file.Version
@@ -160,6 +209,11 @@ func (drv *Driver) Version() (file.Version, error) {
 
 // Versions returns the list of applied migrations.
 func (drv *Driver) Versions() (file.Versions, error) {
+	return drv.VersionsContext(context.Background())
+}
+
+// VersionsContext returns the list of applied migrations.
+func (drv *Driver) VersionsContext(ctx context.Context) (file.Versions, error) {
 	versions := file.Versions{}
 
 	err := drv.initVersionConn()
@@ -167,7 +221,7 @@ func (drv *Driver) Versions() (file.Versions, error) {
 		return nil, err
 	}
 
-	rows, err := drv.versionConn.QueryContext(context.TODO(), "SELECT version FROM "+versionsTableName+" ORDER BY version DESC")
+	rows, err := drv.versionConn.QueryContext(ctx, "SELECT version FROM "+versionsTableName+" ORDER BY version DESC")
 	if err != nil {
 		return versions, err
 	}
@@ -184,6 +238,102 @@ func (drv *Driver) Versions() (file.Versions, error) {
 	return versions, err
 }
 
+// Records returns rows of the version table, newest version first.
+func (drv *Driver) Records() ([]driver.Record, error) {
+	records := []driver.Record{}
+
+	err := drv.initVersionConn()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := drv.versionConn.QueryContext(context.TODO(), "SELECT version, name, checksum, applied_at, duration_ms, applied_by, dirty FROM "+versionsTableName+" ORDER BY version DESC")
+	if err != nil {
+		return records, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var record driver.Record
+		var name, checksum, appliedAt, appliedBy sql.NullString
+		var durationMs sql.NullInt64
+		err := rows.Scan(&record.Version, &name, &checksum, &appliedAt, &durationMs, &appliedBy, &record.Dirty)
+		if err != nil {
+			return records, err
+		}
+		record.Name = name.String
+		record.Checksum = checksum.String
+		record.AppliedAt = parseDatetime(appliedAt.String)
+		record.Duration = time.Duration(durationMs.Int64) * time.Millisecond
+		record.AppliedBy = appliedBy.String
+		records = append(records, record)
+	}
+	err = rows.Err()
+	return records, err
+}
+
+// Dirty returns versions whose migrations didn't complete.
+func (drv *Driver) Dirty() (file.Versions, error) {
+	versions := file.Versions{}
+
+	err := drv.initVersionConn()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := drv.versionConn.QueryContext(context.TODO(), "SELECT version FROM "+versionsTableName+" WHERE dirty ORDER BY version DESC")
+	if err != nil {
+		return versions, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version file.Version
+		if err := rows.Scan(&version); err != nil {
+			return versions, err
+		}
+		versions = append(versions, version)
+	}
+	err = rows.Err()
+	return versions, err
+}
+
+// Force clears dirty mark of given version.
+func (drv *Driver) Force(version file.Version) error {
+	err := drv.initVersionConn()
+	if err != nil {
+		return err
+	}
+	_, err = drv.versionConn.ExecContext(context.TODO(), "UPDATE "+versionsTableName+" SET dirty = false WHERE version = ?", version)
+	return err
+}
+
+// SetAppliedBy sets value recorded in applied_by column.
+func (drv *Driver) SetAppliedBy(appliedBy string) {
+	drv.appliedBy = appliedBy
+}
+
+// QuoteIdentifier quotes identifier for use in SQL, e.g. `my table`.
+func (drv *Driver) QuoteIdentifier(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+// QuoteLiteral quotes string literal for use in SQL,
+// escaping quotes and backslashes.
+func (drv *Driver) QuoteLiteral(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	return `'` + strings.Replace(value, `'`, `''`, -1) + `'`
+}
+
+// parseDatetime parses UTC DATETIME value scanned as string.
+// Depending on parseTime DSN parameter it's either in MySQL or RFC3339 format.
+func parseDatetime(s string) time.Time {
+	for _, layout := range []string{"2006-01-02 15:04:05.999999", time.RFC3339Nano} {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
 // Lock schema_migrations table
 func (drv *Driver) Lock() error {
 	err := drv.initVersionConn()
@@ -229,10 +379,38 @@ func (drv *Driver) ensureVersionTableExists() error {
 	if err = r.Scan(&dataType); err != nil {
 		return err
 	}
-	if dataType != "int" {
+	if dataType == "int" {
+		_, err = drv.db.Exec("ALTER TABLE " + versionsTableName + " MODIFY version bigint")
+		if err != nil {
+			return err
+		}
+	}
+	for _, column := range []struct{ name, definition string }{
+		{"name", "varchar(255)"},
+		{"checksum", "varchar(64)"},
+		{"applied_at", "datetime(6)"},
+		{"duration_ms", "bigint"},
+		{"applied_by", "varchar(255)"},
+		{"dirty", "boolean not null default false"},
+	} {
+		if err := drv.ensureColumnExists(column.name, column.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureColumnExists adds column to the versions table, unless it already exists.
+func (drv *Driver) ensureColumnExists(column, definition string) error {
+	var c int
+	r := drv.db.QueryRow("SELECT count(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?", versionsTableName, column)
+	if err := r.Scan(&c); err != nil {
+		return err
+	}
+	if c > 0 {
 		return nil
 	}
-	_, err = drv.db.Exec("ALTER TABLE " + versionsTableName + " MODIFY version bigint")
+	_, err := drv.db.Exec("ALTER TABLE " + versionsTableName + " ADD COLUMN " + column + " " + definition)
 	return err
 }
 
@@ -241,7 +419,7 @@ func parseDirective(b []byte) string {
 	if !bytes.HasPrefix(b, []byte("-- ")) {
 		return directiveNoop
 	}
-	return string(b[3 : len(b)-1])
+	return string(bytes.TrimSpace(b[3:]))
 }
 
 type migrationSegment struct {
@@ -291,9 +469,15 @@ func parseMigration(b []byte) (*migration, error) {
 			m.noTx = true
 			stmt.tx = true
 			stmt.txbegin = i + 1
-			for ; i < len(lines); i++ {
+			for i++; i < len(lines); i++ {
+				if len(bytes.TrimSpace(lines[i])) == 0 {
+					continue
+				}
 				directive := parseDirective(lines[i])
-				if directive != "" && directive != directiveTxend {
+				if directive == directiveTxend {
+					break
+				}
+				if directive != "" {
 					return nil, fmt.Errorf("expected %q, got %q at line %d", directiveTxend, directive, i+1)
 				}
 				i = writeStmt(&stmt, lines, i)
@@ -308,7 +492,7 @@ func parseMigration(b []byte) (*migration, error) {
 	return m, nil
 }
 
-func (m migration) exec(db *sql.DB) (err error) {
+func (m migration) exec(ctx context.Context, db *sql.DB) (err error) {
 	var tx *sql.Tx
 	defer func() {
 		if err != nil && tx != nil {
@@ -316,13 +500,13 @@ func (m migration) exec(db *sql.DB) (err error) {
 		}
 	}()
 	if !m.noTx {
-		tx, err = db.Begin()
+		tx, err = db.BeginTx(ctx, nil)
 		if err != nil {
 			return err
 		}
 		for _, seg := range m.segments {
 			for i, stmt := range seg.statements {
-				_, err = tx.Exec(stmt)
+				_, err = tx.ExecContext(ctx, stmt)
 				if err != nil {
 					return stmtExecErr(err, stmt, seg.offsets[i])
 				}
@@ -331,19 +515,28 @@ func (m migration) exec(db *sql.DB) (err error) {
 		return tx.Commit()
 	}
 	for _, seg := range m.segments {
-		if seg.tx {
-			tx, err = db.Begin()
-			if err != nil {
-				return err
-			}
+		if !seg.tx {
 			for i, stmt := range seg.statements {
-				_, err = tx.Exec(stmt)
-				if err != nil {
+				if _, err = db.ExecContext(ctx, stmt); err != nil {
 					return stmtExecErr(err, stmt, seg.offsets[i])
 				}
 			}
-			return stmtCommitErr(tx.Commit(), seg)
+			continue
+		}
+		tx, err = db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		for i, stmt := range seg.statements {
+			_, err = tx.ExecContext(ctx, stmt)
+			if err != nil {
+				return stmtExecErr(err, stmt, seg.offsets[i])
+			}
+		}
+		if err = stmtCommitErr(tx.Commit(), seg); err != nil {
+			return err
 		}
+		tx = nil
 	}
 	return nil
 }
@@ -86,6 +86,17 @@ func migrate(t *testing.T, driverURL string) {
 				CREATE TABLE error (
           id THIS WILL CAUSE AN ERROR
         );
+      `),
+		},
+		{
+			Path:      "/foobar",
+			FileName:  "20080000000000_foobar.up.sql",
+			Version:   20080000000000,
+			Name:      "foobar",
+			Direction: direction.Up,
+			Content: []byte(`-- NOTX
+        CREATE TABLE halfway (id int(11));
+        CREATE TABLE error (id THIS WILL CAUSE AN ERROR);
       `),
 		},
 	}
@@ -140,13 +151,49 @@ func migrate(t *testing.T, driverURL string) {
 		t.Errorf("Expected versions to be: %v, got: %v", expectedVersions, versions)
 	}
 
+	// failed migration without transaction leaves its version dirty
+	driver.Lock(d)
+	err = d.Migrate(files[3])
+	if err == nil {
+		t.Error("Expected test case to fail")
+	}
+	driver.Unlock(d)
+	if _, err := d.(*Driver).db.Query("SELECT id FROM halfway"); err != nil {
+		t.Errorf("Expected statement before the failed one to be applied: %v", err)
+	}
+	dirty, err := d.(*Driver).Dirty()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (file.Versions{20080000000000}); !reflect.DeepEqual(dirty, expected) {
+		t.Errorf("Expected dirty versions to be: %v, got: %v", expected, dirty)
+	}
+	if err := d.(*Driver).Force(20080000000000); err != nil {
+		t.Fatal(err)
+	}
+	dirty, err = d.(*Driver).Dirty()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirty) != 0 {
+		t.Errorf("Expected no dirty versions after Force, got: %v", dirty)
+	}
+	expectedVersions = file.Versions{20080000000000}
+	versions, err = d.Versions()
+	if err != nil {
+		t.Errorf("Could not fetch versions: %s", err)
+	}
+	if !reflect.DeepEqual(versions, expectedVersions) {
+		t.Errorf("Expected forced version to stay applied: %v, got: %v", expectedVersions, versions)
+	}
+
 	if err := d.Close(); err != nil {
 		t.Fatal(err)
 	}
 }
 
 func dropTestTables(t *testing.T, db *sql.DB) {
-	if _, err := db.Exec(`DROP TABLE IF EXISTS yolo, yolo1, ` + versionsTableName); err != nil {
+	if _, err := db.Exec(`DROP TABLE IF EXISTS yolo, yolo1, halfway, ` + versionsTableName); err != nil {
 		t.Fatal(err)
 	}
 }
@@ -158,3 +205,54 @@ func getenvDefault(varname, defaultValue string) string {
 	}
 	return v
 }
+
+func TestParseMigration(t *testing.T) {
+	m, err := parseMigration([]byte(`
+-- NOTX
+CREATE TABLE a (id int);
+
+-- TXBEGIN
+INSERT INTO a VALUES (1);
+INSERT INTO a VALUES (2);
+-- TXEND
+
+CREATE TABLE b (id int);
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.noTx {
+		t.Error("Expected migration to have transaction disabled")
+	}
+	if len(m.segments) != 3 {
+		t.Fatalf("Expected 3 segments, got %d: %#v", len(m.segments), m.segments)
+	}
+	for i, expected := range []struct {
+		tx         bool
+		statements int
+	}{{false, 1}, {true, 2}, {false, 1}} {
+		seg := m.segments[i]
+		if seg.tx != expected.tx || len(seg.statements) != expected.statements {
+			t.Errorf("Expected segment %d to have tx %v and %d statements, got %v and %d",
+				i, expected.tx, expected.statements, seg.tx, len(seg.statements))
+		}
+	}
+
+	m, err = parseMigration([]byte("CREATE TABLE a (id int);\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.noTx {
+		t.Error("Expected migration to run in transaction by default")
+	}
+}
+
+func TestQuote(t *testing.T) {
+	d := &Driver{}
+	if q := d.QuoteIdentifier("my`table"); q != "`my``table`" {
+		t.Errorf("unexpected quoted identifier %s", q)
+	}
+	if q := d.QuoteLiteral(`it's C:\temp`); q != `'it''s C:\\temp'` {
+		t.Errorf("unexpected quoted literal %s", q)
+	}
+}
@@ -2,10 +2,12 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/db-journey/migrate/v2/direction"
 	"github.com/db-journey/migrate/v2/driver"
@@ -17,7 +19,8 @@ var fileTemplate = []byte(``) // TODO
 
 // Driver is the postgres driver for journey.
 type Driver struct {
-	db *sql.DB
+	db        *sql.DB
+	appliedBy string
 }
 
 const tableName = "public.schema_migrations"
@@ -51,34 +54,66 @@ func (driver *Driver) Close() error {
 func (driver *Driver) ensureVersionTableExists() error {
 	// avoid DDL statements if possible for BDR (see #23)
 	var c int
-	if err := driver.db.QueryRow("SELECT count(*) FROM information_schema.tables WHERE table_name = $1", tableName).Scan(&c); err != nil {
+	if err := driver.db.QueryRow("SELECT count(*) FROM information_schema.tables WHERE table_schema || '.' || table_name = $1", tableName).Scan(&c); err != nil {
 		return err
 	}
 
 	if c <= 0 {
-		_, err := driver.db.Exec("CREATE TABLE IF NOT EXISTS " + tableName + " (version bigint not null primary key)")
-		return err
+		if _, err := driver.db.Exec("CREATE TABLE IF NOT EXISTS " + tableName + " (version bigint not null primary key)"); err != nil {
+			return err
+		}
 	}
 
 	// table schema_migrations already exists, check if the schema is correct, ie: version is a bigint
 
 	var dataType string
-	if err := driver.db.QueryRow("SELECT data_type FROM information_schema.columns where table_name = $1 and column_name = 'version'", tableName).Scan(&dataType); err != nil {
+	if err := driver.db.QueryRow("SELECT data_type FROM information_schema.columns where table_schema || '.' || table_name = $1 and column_name = 'version'", tableName).Scan(&dataType); err != nil {
 		return err
 	}
 
-	if dataType == "bigint" {
-		return nil
+	if dataType != "bigint" {
+		if _, err := driver.db.Exec("ALTER TABLE " + tableName + " ALTER COLUMN version TYPE bigint USING version::bigint"); err != nil {
+			return err
+		}
 	}
 
-	_, err := driver.db.Exec("ALTER TABLE " + tableName + " ALTER COLUMN version TYPE bigint USING version::bigint")
+	for _, column := range []struct{ name, definition string }{
+		{"name", "text"},
+		{"checksum", "varchar(64)"},
+		{"applied_at", "timestamp with time zone"},
+		{"duration_ms", "bigint"},
+		{"applied_by", "text"},
+		{"dirty", "boolean not null default false"},
+	} {
+		if err := driver.ensureColumnExists(column.name, column.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureColumnExists adds column to the version table, unless it already exists.
+func (driver *Driver) ensureColumnExists(column, definition string) error {
+	var c int
+	if err := driver.db.QueryRow("SELECT count(*) FROM information_schema.columns WHERE table_schema || '.' || table_name = $1 AND column_name = $2", tableName, column).Scan(&c); err != nil {
+		return err
+	}
+	if c > 0 {
+		return nil
+	}
+	_, err := driver.db.Exec("ALTER TABLE " + tableName + " ADD COLUMN " + column + " " + definition)
 	return err
 }
 
 // Migrate performs the migration of any one file.
-func (driver *Driver) Migrate(f file.File) (err error) {
+func (driver *Driver) Migrate(f file.File) error {
+	return driver.MigrateContext(context.Background(), f)
+}
+
+// MigrateContext runs migration, cancelling running statement when ctx is done.
+func (driver *Driver) MigrateContext(ctx context.Context, f file.File) (err error) {
 	var tx *sql.Tx
-	tx, err = driver.db.Begin()
+	tx, err = driver.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
@@ -88,38 +123,174 @@ func (driver *Driver) Migrate(f file.File) (err error) {
 		}
 	}()
 
+	if err = f.ReadContent(); err != nil {
+		return err
+	}
+
+	if txDisabled(fileOptions(f.Content)) {
+		err = driver.migrateNoTx(ctx, tx, f)
+	} else {
+		err = driver.migrate(ctx, tx, f)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// migrate runs migration and updates version table in transaction tx.
+func (driver *Driver) migrate(ctx context.Context, tx *sql.Tx, f file.File) error {
+	start := time.Now()
+	if _, err := tx.ExecContext(ctx, string(f.Content)); err != nil {
+		return migrationError(f, err)
+	}
+
+	var err error
 	if f.Direction == direction.Up {
-		if _, err = tx.Exec("INSERT INTO "+tableName+" (version) VALUES ($1)", f.Version); err != nil {
-			return err
-		}
+		_, err = tx.ExecContext(ctx, "INSERT INTO "+tableName+" (version, name, checksum, applied_at, duration_ms, applied_by) VALUES ($1, $2, $3, $4, $5, $6)",
+			f.Version, f.Name, f.Checksum(), start, time.Since(start).Milliseconds(), driver.appliedBy)
 	} else if f.Direction == direction.Down {
-		if _, err = tx.Exec("DELETE FROM "+tableName+" WHERE version=$1", f.Version); err != nil {
-			return err
-		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM "+tableName+" WHERE version=$1", f.Version)
 	}
+	return err
+}
 
-	if err = f.ReadContent(); err != nil {
+// migrateNoTx runs migration without transaction. Migration can't be rolled back
+// if it fails halfway, so its version is marked dirty until it succeeds.
+// Version table is finally updated in transaction tx.
+func (driver *Driver) migrateNoTx(ctx context.Context, tx *sql.Tx, f file.File) error {
+	start := time.Now()
+	var err error
+	if f.Direction == direction.Up {
+		_, err = driver.db.ExecContext(ctx, "INSERT INTO "+tableName+" (version, name, checksum, applied_at, applied_by, dirty) VALUES ($1, $2, $3, $4, $5, true)",
+			f.Version, f.Name, f.Checksum(), start, driver.appliedBy)
+	} else {
+		_, err = driver.db.ExecContext(ctx, "UPDATE "+tableName+" SET dirty = true WHERE version=$1", f.Version)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err = driver.db.ExecContext(ctx, string(f.Content)); err != nil {
+		return migrationError(f, err)
+	}
+
+	if f.Direction == direction.Up {
+		_, err = tx.ExecContext(ctx, "UPDATE "+tableName+" SET dirty = false, duration_ms = $1 WHERE version=$2", time.Since(start).Milliseconds(), f.Version)
+	} else if f.Direction == direction.Down {
+		_, err = tx.ExecContext(ctx, "DELETE FROM "+tableName+" WHERE version=$1", f.Version)
+	}
+	return err
+}
+
+// migrationError points to position of the error in migration file, if it's known.
+func migrationError(f file.File, err error) error {
+	pqErr, ok := err.(*pq.Error)
+	if !ok {
 		return err
 	}
+	offset, err := strconv.Atoi(pqErr.Position)
+	if err == nil && offset >= 0 {
+		lineNo, columnNo := file.LineColumnFromOffset(f.Content, offset-1)
+		errorPart := file.LinesBeforeAndAfter(f.Content, lineNo, 5, 5, true)
+		return fmt.Errorf("%s %v: %s in line %v, column %v:\n\n%s", pqErr.Severity, pqErr.Code, pqErr.Message, lineNo, columnNo, string(errorPart))
+	}
+	return fmt.Errorf("%s %v: %s", pqErr.Severity, pqErr.Code, pqErr.Message)
+}
 
+// BeginTx starts transaction, in which several migrations can be run.
+// Migrations with disable_ddl_transaction option are refused in it.
+func (drv *Driver) BeginTx(ctx context.Context) (driver.Tx, error) {
+	tx, err := drv.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &migrationTx{drv: drv, tx: tx}, nil
+}
+
+// migrationTx runs migrations in single transaction.
+type migrationTx struct {
+	drv *Driver
+	tx  *sql.Tx
+}
+
+// MigrateContext runs migration within transaction.
+func (t *migrationTx) MigrateContext(ctx context.Context, f file.File) error {
+	if err := f.ReadContent(); err != nil {
+		return err
+	}
 	if txDisabled(fileOptions(f.Content)) {
-		_, err = driver.db.Exec(string(f.Content))
-	} else {
-		_, err = tx.Exec(string(f.Content))
+		return fmt.Errorf("migration %s has %s option, it can't run in single transaction", f.FileName, txDisabledOption)
+	}
+	return t.drv.migrate(ctx, t.tx, f)
+}
+
+// VersionsContext returns the list of applied migrations as seen within transaction.
+func (t *migrationTx) VersionsContext(ctx context.Context) (file.Versions, error) {
+	return versions(ctx, t.tx)
+}
+
+func (t *migrationTx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *migrationTx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+// MarkApplied records version of given up migration file without running it.
+func (driver *Driver) MarkApplied(f file.File) error {
+	if err := f.ReadContent(); err != nil {
+		return err
 	}
+	_, err := driver.db.Exec("INSERT INTO "+tableName+" (version, name, checksum, applied_at, applied_by) VALUES ($1, $2, $3, $4, $5)",
+		f.Version, f.Name, f.Checksum(), time.Now(), driver.appliedBy)
+	return err
+}
 
+// Dirty returns versions whose migrations didn't complete.
+func (driver *Driver) Dirty() (file.Versions, error) {
+	rows, err := driver.db.Query("SELECT version FROM " + tableName + " WHERE dirty ORDER BY version DESC")
 	if err != nil {
-		pqErr := err.(*pq.Error)
-		offset, err := strconv.Atoi(pqErr.Position)
-		if err == nil && offset >= 0 {
-			lineNo, columnNo := file.LineColumnFromOffset(f.Content, offset-1)
-			errorPart := file.LinesBeforeAndAfter(f.Content, lineNo, 5, 5, true)
-			return fmt.Errorf("%s %v: %s in line %v, column %v:\n\n%s", pqErr.Severity, pqErr.Code, pqErr.Message, lineNo, columnNo, string(errorPart))
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := file.Versions{}
+	for rows.Next() {
+		var version file.Version
+		if err = rows.Scan(&version); err != nil {
+			return nil, err
 		}
-		return fmt.Errorf("%s %v: %s", pqErr.Severity, pqErr.Code, pqErr.Message)
+		versions = append(versions, version)
 	}
 
-	return tx.Commit()
+	return versions, rows.Err()
+}
+
+// Force clears dirty mark of given version.
+func (driver *Driver) Force(version file.Version) error {
+	_, err := driver.db.Exec("UPDATE "+tableName+" SET dirty = false WHERE version=$1", version)
+	return err
+}
+
+// SetAppliedBy sets value recorded in applied_by column.
+func (driver *Driver) SetAppliedBy(appliedBy string) {
+	driver.appliedBy = appliedBy
+}
+
+// QuoteIdentifier quotes identifier for use in SQL, e.g. "my schema".
+func (driver *Driver) QuoteIdentifier(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// QuoteLiteral quotes string literal for use in SQL, using escape string
+// syntax if value contains backslashes, e.g. E'C:\\temp'.
+func (driver *Driver) QuoteLiteral(value string) string {
+	value = strings.Replace(value, `'`, `''`, -1)
+	if strings.Contains(value, `\`) {
+		return `E'` + strings.Replace(value, `\`, `\\`, -1) + `'`
+	}
+	return `'` + value + `'`
 }
 
 // Version returns the current migration version.
@@ -135,7 +306,22 @@ func (driver *Driver) Version() (file.Version, error) {
 
 // Versions returns the list of applied migrations.
 func (driver *Driver) Versions() (file.Versions, error) {
-	rows, err := driver.db.Query("SELECT version FROM " + tableName + " ORDER BY version DESC")
+	return driver.VersionsContext(context.Background())
+}
+
+// VersionsContext returns the list of applied migrations.
+func (driver *Driver) VersionsContext(ctx context.Context) (file.Versions, error) {
+	return versions(ctx, driver.db)
+}
+
+// querier is either *sql.DB or *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// versions returns the list of applied migrations.
+func versions(ctx context.Context, q querier) (file.Versions, error) {
+	rows, err := q.QueryContext(ctx, "SELECT version FROM "+tableName+" ORDER BY version DESC")
 	if err != nil {
 		return nil, err
 	}
@@ -157,9 +343,42 @@ func (driver *Driver) Versions() (file.Versions, error) {
 	return versions, err
 }
 
+// Records returns rows of the version table, newest version first.
+func (drv *Driver) Records() ([]driver.Record, error) {
+	rows, err := drv.db.Query("SELECT version, name, checksum, applied_at, duration_ms, applied_by, dirty FROM " + tableName + " ORDER BY version DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []driver.Record{}
+	for rows.Next() {
+		var record driver.Record
+		var name, checksum, appliedBy sql.NullString
+		var appliedAt sql.NullTime
+		var durationMs sql.NullInt64
+		if err = rows.Scan(&record.Version, &name, &checksum, &appliedAt, &durationMs, &appliedBy, &record.Dirty); err != nil {
+			return nil, err
+		}
+		record.Name = name.String
+		record.Checksum = checksum.String
+		record.AppliedAt = appliedAt.Time
+		record.Duration = time.Duration(durationMs.Int64) * time.Millisecond
+		record.AppliedBy = appliedBy.String
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
 // Execute a SQL statement
 func (driver *Driver) Execute(statement string) error {
-	_, err := driver.db.Exec(statement)
+	return driver.ExecuteContext(context.Background(), statement)
+}
+
+// ExecuteContext executes a SQL statement, cancelling it when ctx is done.
+func (driver *Driver) ExecuteContext(ctx context.Context, statement string) error {
+	_, err := driver.db.ExecContext(ctx, statement)
 	return err
 }
 
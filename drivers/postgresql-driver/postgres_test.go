@@ -1,9 +1,11 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/db-journey/migrate/v2/direction"
@@ -105,6 +107,18 @@ func migrate(t *testing.T, driverURL string) {
 				)
 			`),
 		},
+		{
+			Path:      "/foobar",
+			FileName:  "20060102150408_foobar.up.sql",
+			Version:   20060102150408,
+			Name:      "foobar",
+			Direction: direction.Up,
+			Content: []byte(`-- disable_ddl_transaction
+				CREATE TABLE error (
+					id THIS WILL CAUSE AN ERROR
+				)
+			`),
+		},
 	}
 
 	// should create table yolo
@@ -193,16 +207,147 @@ func migrate(t *testing.T, driverURL string) {
 		t.Errorf("Expected versions to be: %v, got: %v", expectedVersions, versions)
 	}
 
+	// failed migration without transaction leaves its version dirty
+	err = d.Migrate(files[5])
+	if err == nil {
+		t.Error("Expected test case to fail")
+	}
+	dirty, err := d.(*Driver).Dirty()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (file.Versions{20060102150408}); !reflect.DeepEqual(dirty, expected) {
+		t.Errorf("Expected dirty versions to be: %v, got: %v", expected, dirty)
+	}
+	if err := d.(*Driver).Force(20060102150408); err != nil {
+		t.Fatal(err)
+	}
+	dirty, err = d.(*Driver).Dirty()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirty) != 0 {
+		t.Errorf("Expected no dirty versions after Force, got: %v", dirty)
+	}
+	expectedVersions = file.Versions{20060102150408}
+	versions, err = d.Versions()
+	if err != nil {
+		t.Errorf("Could not fetch versions: %s", err)
+	}
+	if !reflect.DeepEqual(versions, expectedVersions) {
+		t.Errorf("Expected forced version to stay applied: %v, got: %v", expectedVersions, versions)
+	}
+
 	if err := d.Close(); err != nil {
 		t.Fatal(err)
 	}
 
 }
 
+func TestBeginTx(t *testing.T) {
+	host := getenvDefault("POSTGRES_PORT_5432_TCP_ADDR", "localhost")
+	port := getenvDefault("POSTGRES_PORT_5432_TCP_PORT", "5432")
+	driverURL := "postgres://postgres:migrate@" + host + ":" + port + "/template1?sslmode=disable"
+
+	connection, err := sql.Open("postgres", driverURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer connection.Close()
+	dropTestTables(t, connection)
+	defer dropTestTables(t, connection)
+
+	d, err := Open(driverURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	files := []file.File{
+		{
+			FileName:  "001_first.up.sql",
+			Version:   1,
+			Name:      "first",
+			Direction: direction.Up,
+			Content:   []byte("CREATE TABLE tx_first (id integer);"),
+		},
+		{
+			FileName:  "002_second.up.sql",
+			Version:   2,
+			Name:      "second",
+			Direction: direction.Up,
+			Content:   []byte("CREATE TABLE tx_second (id integer);"),
+		},
+	}
+
+	for _, commit := range []bool{false, true} {
+		tx, err := d.(*Driver).BeginTx(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range files {
+			if err := tx.MigrateContext(context.Background(), f); err != nil {
+				t.Fatal(err)
+			}
+		}
+		versions, err := tx.VersionsContext(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(versions, file.Versions{2, 1}) {
+			t.Errorf("Expected versions within transaction to be: %v, got: %v", file.Versions{2, 1}, versions)
+		}
+		expectedVersions := file.Versions{}
+		if commit {
+			err = tx.Commit()
+			expectedVersions = file.Versions{2, 1}
+		} else {
+			err = tx.Rollback()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions, err = d.Versions()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(versions, expectedVersions) {
+			t.Errorf("Expected versions to be: %v, got: %v", expectedVersions, versions)
+		}
+	}
+
+	// migration without transaction can't run in single transaction
+	tx, err := d.(*Driver).BeginTx(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tx.MigrateContext(context.Background(), file.File{
+		FileName:  "003_third.up.sql",
+		Version:   3,
+		Name:      "third",
+		Direction: direction.Up,
+		Content:   []byte("-- disable_ddl_transaction\nCREATE TABLE tx_third (id integer);"),
+	})
+	if err == nil || !strings.Contains(err.Error(), "can't run in single transaction") {
+		t.Errorf("Expected migration with disable_ddl_transaction to be refused, got %v", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	versions, err := d.Versions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(versions, file.Versions{2, 1}) {
+		t.Errorf("Expected versions to be: %v, got: %v", file.Versions{2, 1}, versions)
+	}
+}
+
 func dropTestTables(t *testing.T, db *sql.DB) {
 	if _, err := db.Exec(`
 				DROP TYPE IF EXISTS colors;
 				DROP TABLE IF EXISTS yolo;
+				DROP TABLE IF EXISTS tx_first, tx_second, tx_third;
 				DROP TABLE IF EXISTS ` + tableName + `;`); err != nil {
 		t.Fatal(err)
 	}
@@ -216,3 +361,16 @@ func getenvDefault(varname, defaultValue string) string {
 	}
 	return v
 }
+
+func TestQuote(t *testing.T) {
+	d := &Driver{}
+	if q := d.QuoteIdentifier(`my "schema"`); q != `"my ""schema"""` {
+		t.Errorf("unexpected quoted identifier %s", q)
+	}
+	if q := d.QuoteLiteral("it's"); q != `'it''s'` {
+		t.Errorf("unexpected quoted literal %s", q)
+	}
+	if q := d.QuoteLiteral(`C:\temp`); q != `E'C:\\temp'` {
+		t.Errorf("unexpected quoted literal %s", q)
+	}
+}
@@ -2,10 +2,12 @@
 package sqlite3
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/db-journey/migrate/v2/direction"
 	"github.com/db-journey/migrate/v2/driver"
@@ -14,7 +16,8 @@ import (
 )
 
 type Driver struct {
-	db *sql.DB
+	db        *sql.DB
+	appliedBy string
 }
 
 const tableName = "schema_migration"
@@ -52,11 +55,55 @@ func (driver *Driver) ensureVersionTableExists() error {
 	if _, err := driver.db.Exec("CREATE TABLE IF NOT EXISTS " + tableName + " (version INTEGER PRIMARY KEY AUTOINCREMENT);"); err != nil {
 		return err
 	}
+	for _, column := range []struct{ name, definition string }{
+		{"name", "TEXT"},
+		{"checksum", "TEXT"},
+		{"applied_at", "TIMESTAMP"},
+		{"duration_ms", "INTEGER"},
+		{"applied_by", "TEXT"},
+	} {
+		if err := driver.ensureColumnExists(column.name, column.definition); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// ensureColumnExists adds column to the version table, unless it already exists.
+func (driver *Driver) ensureColumnExists(column, definition string) error {
+	rows, err := driver.db.Query("PRAGMA table_info(" + tableName + ")")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, colType    string
+			defaultValue     sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	_, err = driver.db.Exec("ALTER TABLE " + tableName + " ADD COLUMN " + column + " " + definition)
+	return err
+}
+
+// Migrate runs migration in transaction.
 func (driver *Driver) Migrate(f file.File) error {
-	tx, err := driver.db.Begin()
+	return driver.MigrateContext(context.Background(), f)
+}
+
+// MigrateContext runs migration in transaction, interrupting it when ctx is done.
+func (driver *Driver) MigrateContext(ctx context.Context, f file.File) error {
+	tx, err := driver.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
@@ -67,23 +114,22 @@ func (driver *Driver) Migrate(f file.File) error {
 		}
 	}()
 
-	if f.Direction == direction.Up {
-		if _, err = tx.Exec("INSERT INTO "+tableName+" (version) VALUES (?)", f.Version); err != nil {
-			return err
-		}
-	} else if f.Direction == direction.Down {
-		if _, err = tx.Exec("DELETE FROM "+tableName+" WHERE version=?", f.Version); err != nil {
-			return err
-		}
+	if err = driver.migrate(ctx, tx, f); err != nil {
+		return err
 	}
+	return tx.Commit()
+}
 
-	if err = f.ReadContent(); err != nil {
+// migrate runs migration and updates version table in transaction tx.
+func (driver *Driver) migrate(ctx context.Context, tx *sql.Tx, f file.File) error {
+	if err := f.ReadContent(); err != nil {
 		return err
 	}
 
+	start := time.Now()
 	queries := splitStatements(string(f.Content))
 	for _, query := range queries {
-		if _, err := tx.Exec(query); err != nil {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
 			sqliteErr, isErr := err.(gosqlite3.Error)
 			if isErr {
 				// The sqlite3 library only provides error codes, not position information. Output what we do know.
@@ -94,7 +140,72 @@ func (driver *Driver) Migrate(f file.File) error {
 		}
 	}
 
-	return tx.Commit()
+	var err error
+	if f.Direction == direction.Up {
+		_, err = tx.ExecContext(ctx, "INSERT INTO "+tableName+" (version, name, checksum, applied_at, duration_ms, applied_by) VALUES (?, ?, ?, ?, ?, ?)",
+			f.Version, f.Name, f.Checksum(), start.UTC(), time.Since(start).Milliseconds(), driver.appliedBy)
+	} else if f.Direction == direction.Down {
+		_, err = tx.ExecContext(ctx, "DELETE FROM "+tableName+" WHERE version=?", f.Version)
+	}
+	return err
+}
+
+// BeginTx starts transaction, in which several migrations can be run.
+func (drv *Driver) BeginTx(ctx context.Context) (driver.Tx, error) {
+	tx, err := drv.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &migrationTx{drv: drv, tx: tx}, nil
+}
+
+// migrationTx runs migrations in single transaction.
+type migrationTx struct {
+	drv *Driver
+	tx  *sql.Tx
+}
+
+// MigrateContext runs migration within transaction.
+func (t *migrationTx) MigrateContext(ctx context.Context, f file.File) error {
+	return t.drv.migrate(ctx, t.tx, f)
+}
+
+// VersionsContext returns the list of applied migrations as seen within transaction.
+func (t *migrationTx) VersionsContext(ctx context.Context) (file.Versions, error) {
+	return versions(ctx, t.tx)
+}
+
+func (t *migrationTx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *migrationTx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+// MarkApplied records version of given up migration file without running it.
+func (driver *Driver) MarkApplied(f file.File) error {
+	if err := f.ReadContent(); err != nil {
+		return err
+	}
+	_, err := driver.db.Exec("INSERT INTO "+tableName+" (version, name, checksum, applied_at, applied_by) VALUES (?, ?, ?, ?, ?)",
+		f.Version, f.Name, f.Checksum(), time.Now().UTC(), driver.appliedBy)
+	return err
+}
+
+// SetAppliedBy sets value recorded in applied_by column.
+func (driver *Driver) SetAppliedBy(appliedBy string) {
+	driver.appliedBy = appliedBy
+}
+
+// QuoteIdentifier quotes identifier for use in SQL, e.g. "my table".
+func (driver *Driver) QuoteIdentifier(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// QuoteLiteral quotes string literal for use in SQL.
+func (driver *Driver) QuoteLiteral(value string) string {
+	return `'` + strings.Replace(value, `'`, `''`, -1) + `'`
 }
 
 // Version returns the current migration version.
@@ -113,9 +224,24 @@ func (driver *Driver) Version() (file.Version, error) {
 
 // Versions returns the list of applied migrations.
 func (driver *Driver) Versions() (file.Versions, error) {
+	return driver.VersionsContext(context.Background())
+}
+
+// VersionsContext returns the list of applied migrations.
+func (driver *Driver) VersionsContext(ctx context.Context) (file.Versions, error) {
+	return versions(ctx, driver.db)
+}
+
+// querier is either *sql.DB or *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// versions returns the list of applied migrations.
+func versions(ctx context.Context, q querier) (file.Versions, error) {
 	versions := file.Versions{}
 
-	rows, err := driver.db.Query("SELECT version FROM " + tableName + " ORDER BY version DESC")
+	rows, err := q.QueryContext(ctx, "SELECT version FROM "+tableName+" ORDER BY version DESC")
 	if err != nil {
 		return versions, err
 	}
@@ -132,9 +258,43 @@ func (driver *Driver) Versions() (file.Versions, error) {
 	return versions, err
 }
 
+// Records returns rows of the version table, newest version first.
+func (drv *Driver) Records() ([]driver.Record, error) {
+	records := []driver.Record{}
+
+	rows, err := drv.db.Query("SELECT version, name, checksum, applied_at, duration_ms, applied_by FROM " + tableName + " ORDER BY version DESC")
+	if err != nil {
+		return records, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var record driver.Record
+		var name, checksum, appliedBy sql.NullString
+		var appliedAt sql.NullTime
+		var durationMs sql.NullInt64
+		err := rows.Scan(&record.Version, &name, &checksum, &appliedAt, &durationMs, &appliedBy)
+		if err != nil {
+			return records, err
+		}
+		record.Name = name.String
+		record.Checksum = checksum.String
+		record.AppliedAt = appliedAt.Time
+		record.Duration = time.Duration(durationMs.Int64) * time.Millisecond
+		record.AppliedBy = appliedBy.String
+		records = append(records, record)
+	}
+	err = rows.Err()
+	return records, err
+}
+
 // Execute a SQL statement
 func (driver *Driver) Execute(statement string) error {
-	_, err := driver.db.Exec(statement)
+	return driver.ExecuteContext(context.Background(), statement)
+}
+
+// ExecuteContext executes a SQL statement, interrupting it when ctx is done.
+func (driver *Driver) ExecuteContext(ctx context.Context, statement string) error {
+	_, err := driver.db.ExecContext(ctx, statement)
 	return err
 }
 
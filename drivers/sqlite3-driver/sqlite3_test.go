@@ -1,10 +1,13 @@
 package sqlite3
 
 import (
+	"context"
+	"database/sql"
 	"io/ioutil"
 	"os"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/db-journey/migrate/v2/direction"
 	"github.com/db-journey/migrate/v2/driver"
@@ -73,6 +76,8 @@ func TestMigrate(t *testing.T) {
 		},
 	}
 
+	d.(*Driver).SetAppliedBy("deployer")
+	before := time.Now().UTC()
 	err = d.Migrate(files[0])
 	if err != nil {
 		t.Fatal(err)
@@ -96,6 +101,25 @@ func TestMigrate(t *testing.T) {
 		t.Errorf("Expected versions to be: %v, got: %v", expectedVersions, versions)
 	}
 
+	records, err := d.(*Driver).Records()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got: %+v", records)
+	}
+	r := records[0]
+	if r.Version != files[0].Version || r.Name != "foobar" || r.Checksum != files[0].Checksum() || r.AppliedBy != "deployer" {
+		t.Errorf("Expected version, name, checksum and applied_by of %s to be recorded, got: %+v", files[0].FileName, r)
+	}
+	if r.AppliedAt.Before(before.Truncate(time.Second)) || r.AppliedAt.After(time.Now().UTC()) {
+		t.Errorf("Expected applied_at between %s and now, got: %s", before, r.AppliedAt)
+	}
+	var hasDuration bool
+	if err := d.(*Driver).db.QueryRow("SELECT duration_ms IS NOT NULL FROM " + tableName).Scan(&hasDuration); err != nil || !hasDuration {
+		t.Errorf("Expected duration_ms to be recorded, got %v, %v", hasDuration, err)
+	}
+
 	err = d.Migrate(files[1])
 	if err != nil {
 		t.Fatal(err)
@@ -119,6 +143,22 @@ func TestMigrate(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	// Baseline version without running its migration.
+	if err := d.(*Driver).MarkApplied(files[0]); err != nil {
+		t.Fatal(err)
+	}
+	expectedVersions = file.Versions{files[1].Version, files[0].Version}
+	versions, err = d.Versions()
+	if err != nil {
+		t.Errorf("Could not fetch versions: %s", err)
+	}
+	if !reflect.DeepEqual(versions, expectedVersions) {
+		t.Errorf("Expected versions to be: %v, got: %v", expectedVersions, versions)
+	}
+	if _, err := d.(*Driver).db.Query("SELECT id FROM yolo"); err == nil {
+		t.Error("Expected migration not to be run")
+	}
+
 	err = d.Migrate(files[3])
 	if err == nil {
 		t.Error("Expected test case to fail")
@@ -129,6 +169,63 @@ func TestMigrate(t *testing.T) {
 	}
 }
 
+// TestUpgradeVersionTable checks that version table of older releases,
+// with version column only, gets new columns without losing data.
+func TestUpgradeVersionTable(t *testing.T) {
+	f, err := ioutil.TempFile(os.TempDir(), "migrate_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	db, err := sql.Open("sqlite3", f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		"CREATE TABLE " + tableName + " (version INTEGER PRIMARY KEY AUTOINCREMENT);",
+		"INSERT INTO " + tableName + " (version) VALUES (1);",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := Open("sqlite3://" + f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	err = d.Migrate(file.File{
+		FileName:  "002_second.up.sql",
+		Version:   2,
+		Name:      "second",
+		Direction: direction.Up,
+		Content:   []byte("CREATE TABLE second (id INTEGER);"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := d.(*Driver).Records()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got: %+v", records)
+	}
+	if r := records[0]; r.Version != 2 || r.Name != "second" || r.Checksum == "" || r.AppliedAt.IsZero() {
+		t.Errorf("Expected new migration to be fully recorded, got: %+v", r)
+	}
+	if r := records[1]; r != (driver.Record{Version: 1}) {
+		t.Errorf("Expected old version to be kept with empty columns, got: %+v", r)
+	}
+}
+
 func TestSplitStatements(t *testing.T) {
 	testCases := []struct {
 		name string
@@ -151,3 +248,70 @@ func TestSplitStatements(t *testing.T) {
 		}
 	}
 }
+
+func TestBeginTx(t *testing.T) {
+	f, err := ioutil.TempFile(os.TempDir(), "migrate_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	d, err := Open("sqlite3://" + f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	files := []file.File{
+		{
+			FileName:  "001_first.up.sql",
+			Version:   1,
+			Name:      "first",
+			Direction: direction.Up,
+			Content:   []byte("CREATE TABLE first (id INTEGER);"),
+		},
+		{
+			FileName:  "002_second.up.sql",
+			Version:   2,
+			Name:      "second",
+			Direction: direction.Up,
+			Content:   []byte("CREATE TABLE second (id INTEGER);"),
+		},
+	}
+
+	for _, commit := range []bool{false, true} {
+		tx, err := d.(*Driver).BeginTx(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range files {
+			if err := tx.MigrateContext(context.Background(), f); err != nil {
+				t.Fatal(err)
+			}
+		}
+		versions, err := tx.VersionsContext(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(versions, file.Versions{2, 1}) {
+			t.Errorf("Expected versions within transaction to be: %v, got: %v", file.Versions{2, 1}, versions)
+		}
+		expectedVersions := file.Versions{}
+		if commit {
+			err = tx.Commit()
+			expectedVersions = file.Versions{2, 1}
+		} else {
+			err = tx.Rollback()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions, err = d.Versions()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(versions, expectedVersions) {
+			t.Errorf("Expected versions to be: %v, got: %v", expectedVersions, versions)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OpenArchive reads .tar, .tar.gz, .tgz or .zip archive into memory
+// and returns its contents as read only fs.FS.
+// Paths within fs.FS, and so in errors, are relative to the archive root.
+func OpenArchive(name string) (fs.FS, error) {
+	var read func(data []byte) (fs.FS, error)
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		read = func(data []byte) (fs.FS, error) {
+			zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				return nil, err
+			}
+			return zr, nil
+		}
+	case strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz"):
+		read = func(data []byte) (fs.FS, error) {
+			r, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			return readTar(r)
+		}
+	case strings.HasSuffix(lower, ".tar"):
+		read = func(data []byte) (fs.FS, error) {
+			return readTar(bytes.NewReader(data))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported archive %s: expected .tar, .tar.gz, .tgz or .zip", name)
+	}
+
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	fsys, err := read(data)
+	if err != nil {
+		return nil, fmt.Errorf("reading archive %s: %w", name, err)
+	}
+	return fsys, nil
+}
+
+// tarFS is in memory file system with tar archive contents,
+// zip.Reader is fs.FS itself.
+type tarFS struct {
+	files map[string][]byte
+	// dirs maps directory to names of its entries,
+	// telling if entry is directory itself.
+	dirs map[string]map[string]bool
+}
+
+func readTar(r io.Reader) (fs.FS, error) {
+	a := &tarFS{
+		files: map[string][]byte{},
+		dirs:  map[string]map[string]bool{".": {}},
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return a, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := a.add(hdr.Name, nil, true); err != nil {
+				return nil, err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			data, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", hdr.Name, err)
+			}
+			if err := a.add(hdr.Name, data, false); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// add adds file or directory with all its parent directories.
+// Leading / is stripped like ./, archive root is the root of fs.
+func (a *tarFS) add(name string, data []byte, isDir bool) error {
+	clean := path.Clean(strings.TrimLeft(name, "/"))
+	if !fs.ValidPath(clean) {
+		return fmt.Errorf("invalid path %q", name)
+	}
+	if clean == "." {
+		return nil
+	}
+	if _, ok := a.files[clean]; ok {
+		return fmt.Errorf("duplicate path %q", name)
+	}
+	if _, ok := a.dirs[clean]; ok && !isDir {
+		return fmt.Errorf("duplicate path %q", name)
+	}
+	if isDir {
+		if a.dirs[clean] == nil {
+			a.dirs[clean] = map[string]bool{}
+		}
+	} else {
+		a.files[clean] = data
+	}
+	for clean != "." {
+		dir := path.Dir(clean)
+		if _, ok := a.files[dir]; ok {
+			return fmt.Errorf("path %q is both file and directory", dir)
+		}
+		if a.dirs[dir] == nil {
+			a.dirs[dir] = map[string]bool{}
+		}
+		a.dirs[dir][path.Base(clean)] = isDir
+		clean, isDir = dir, true
+	}
+	return nil
+}
+
+// Open implements fs.FS.
+func (a *tarFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if data, ok := a.files[name]; ok {
+		return &tarFile{
+			info:   tarInfo{name: path.Base(name), size: int64(len(data))},
+			Reader: bytes.NewReader(data),
+		}, nil
+	}
+	entries, err := a.ReadDir(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &tarDir{
+		info:    tarInfo{name: path.Base(name), dir: true},
+		path:    name,
+		entries: entries,
+	}, nil
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (a *tarFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	children, ok := a.dirs[name]
+	if !ok || !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	entries := make([]fs.DirEntry, 0, len(children))
+	for child, isDir := range children {
+		info := tarInfo{name: child, dir: isDir}
+		if !isDir {
+			info.size = int64(len(a.files[path.Join(name, child)]))
+		}
+		entries = append(entries, info)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// ReadFile implements fs.ReadFileFS.
+func (a *tarFS) ReadFile(name string) ([]byte, error) {
+	data, ok := a.files[name]
+	if !ok || !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// tarInfo is both fs.FileInfo and fs.DirEntry of archived file.
+type tarInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (i tarInfo) Name() string               { return i.name }
+func (i tarInfo) Size() int64                { return i.size }
+func (i tarInfo) ModTime() time.Time         { return time.Time{} }
+func (i tarInfo) IsDir() bool                { return i.dir }
+func (i tarInfo) Sys() interface{}           { return nil }
+func (i tarInfo) Type() fs.FileMode          { return i.Mode().Type() }
+func (i tarInfo) Info() (fs.FileInfo, error) { return i, nil }
+
+func (i tarInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+type tarFile struct {
+	*bytes.Reader
+	info tarInfo
+}
+
+func (f *tarFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *tarFile) Close() error               { return nil }
+
+type tarDir struct {
+	info    tarInfo
+	path    string
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *tarDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *tarDir) Close() error               { return nil }
+
+func (d *tarDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.path, Err: errors.New("is a directory")}
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *tarDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
@@ -0,0 +1,135 @@
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+var archiveFiles = []struct {
+	name, content string
+}{
+	{"migrations/", ""},
+	{"migrations/001_first.up.sql", "CREATE TABLE first;"},
+	{"migrations/001_first.down.sql", "DROP TABLE first;"},
+	{"./migrations/002_second.up.sql.tpl", `CREATE TABLE {{ "second" }};`},
+	{"/migrations/003_third.up.sql", "CREATE TABLE third;"},
+}
+
+func writeTar(w io.Writer) error {
+	tw := tar.NewWriter(w)
+	for _, f := range archiveFiles {
+		hdr := &tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.content)), Typeflag: tar.TypeReg}
+		if strings.HasSuffix(f.name, "/") {
+			hdr.Typeflag, hdr.Mode = tar.TypeDir, 0755
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write([]byte(f.content)); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func writeZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	for _, f := range archiveFiles {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write([]byte(f.content)); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func TestOpenArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp", "TestOpenArchive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var tarBuf, tgzBuf, zipBuf bytes.Buffer
+	if err := writeTar(&tarBuf); err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(&tgzBuf)
+	if err := writeTar(gz); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeZip(&zipBuf); err != nil {
+		t.Fatal(err)
+	}
+	archives := map[string][]byte{
+		"migrations.tar":    tarBuf.Bytes(),
+		"migrations.tar.gz": tgzBuf.Bytes(),
+		"migrations.tgz":    tgzBuf.Bytes(),
+		"migrations.zip":    zipBuf.Bytes(),
+	}
+
+	for name, data := range archives {
+		t.Run(name, func(t *testing.T) {
+			archive := path.Join(dir, name)
+			if err := ioutil.WriteFile(archive, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			fsys, err := OpenArchive(archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := fstest.TestFS(fsys, "migrations/001_first.up.sql", "migrations/001_first.down.sql", "migrations/002_second.up.sql.tpl", "migrations/003_third.up.sql"); err != nil {
+				t.Fatal(err)
+			}
+
+			files, err := ReadMigrationFilesWith("migrations", FilenameRegex("sql"), Options{FS: fsys})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) != 3 {
+				t.Fatalf("expected 3 migration files, got %d", len(files))
+			}
+			up := files[1].UpFile
+			if err := up.ReadContent(); err != nil {
+				t.Fatal(err)
+			}
+			if string(up.Content) != "CREATE TABLE second;" {
+				t.Errorf("expected rendered template, got %q", up.Content)
+			}
+			if up.Path != "migrations" {
+				t.Errorf("expected archive relative path, got %q", up.Path)
+			}
+		})
+	}
+
+	if _, err := OpenArchive(path.Join(dir, "migrations.rar")); err == nil {
+		t.Error("expected error for unsupported archive")
+	}
+
+	var evil bytes.Buffer
+	tw := tar.NewWriter(&evil)
+	tw.WriteHeader(&tar.Header{Name: "../001_evil.up.sql", Mode: 0644, Typeflag: tar.TypeReg})
+	tw.Close()
+	evilPath := path.Join(dir, "evil.tar")
+	if err := ioutil.WriteFile(evilPath, evil.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenArchive(evilPath); err == nil || !strings.Contains(err.Error(), "../001_evil.up.sql") {
+		t.Errorf("expected invalid path error, got %v", err)
+	}
+}
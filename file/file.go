@@ -3,9 +3,12 @@ package file
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"go/token"
+	"io/fs"
 	"io/ioutil"
 	"os"
 	"path"
@@ -14,7 +17,6 @@ import (
 	"sort"
 	"strconv"
 	"strings"
-	"text/template"
 
 	"github.com/db-journey/migrate/v2/direction"
 )
@@ -57,12 +59,20 @@ func (versions Versions) Swap(i, j int) {
 	versions[i], versions[j] = versions[j], versions[i]
 }
 
-// File represents one file on disk.
+// File represents one file on disk or in fs.FS.
 // Example: 20060102150405_initial_plan_to_do_sth.up.sql
 type File struct {
-	// absolute path to file
+	// file system the file is read from, nil for disk
+	FS fs.FS
+
+	// path to directory containing the file,
+	// slash separated path within FS if it's set
 	Path string
 
+	// migrations directory the file was read from,
+	// tells apart files merged from several directories
+	Source string
+
 	// the name of the file
 	FileName string
 
@@ -75,6 +85,9 @@ type File struct {
 	// content of the file
 	Content []byte
 
+	// options for rendering template, nil for defaults
+	Render *RenderOptions
+
 	// UP or DOWN migration
 	Direction direction.Direction
 }
@@ -97,40 +110,61 @@ type MigrationFile struct {
 // MigrationFiles is a slice of MigrationFiles.
 type MigrationFiles []MigrationFile
 
+// IrreversibleDirective marks migration which can't be rolled back,
+// when it's found in the first line of up file, e.g. "-- migrate:irreversible".
+const IrreversibleDirective = "migrate:irreversible"
+
+// IrreversibleError is returned when down migration would have to roll back
+// migration which can't be rolled back.
+type IrreversibleError struct {
+	Version Version
+	Reason  string
+}
+
+func (e *IrreversibleError) Error() string {
+	return fmt.Sprintf("migration %d is irreversible: %s", e.Version, e.Reason)
+}
+
+// CheckReversible returns *IrreversibleError if migration has no down file
+// or its up file is marked with IrreversibleDirective.
+func (mf MigrationFile) CheckReversible() error {
+	if mf.UpFile != nil {
+		content := mf.UpFile.Content
+		if len(content) == 0 {
+			// template isn't rendered, directive is expected to be plain text
+			var err error
+			if content, err = mf.UpFile.readRaw(); err != nil {
+				return err
+			}
+		}
+		firstLine := bytes.SplitN(content, []byte("\n"), 2)[0]
+		if bytes.Contains(firstLine, []byte(IrreversibleDirective)) {
+			return &IrreversibleError{Version: mf.Version, Reason: "up file is marked with " + IrreversibleDirective}
+		}
+	}
+	if mf.DownFile == nil {
+		return &IrreversibleError{Version: mf.Version, Reason: "no down migration file"}
+	}
+	return nil
+}
+
 // ReadContent reads the file into the content if it's empty.
 func (f *File) ReadContent() error {
 	if len(f.Content) == 0 {
-		content, err := ioutil.ReadFile(path.Join(f.Path, f.FileName))
+		content, err := f.readRaw()
 		if err != nil {
 			return err
 		}
 
-		// if the file is a template (extension = ".tpl"), it will be parsed and executed with the program current env
+		// if the file is a template (extension = ".tpl"), it will be parsed and executed
+		// with the program current env or data set in Render
 		if f.IsTemplate() {
-			tmpl, err := template.New(f.Name).Parse(string(content[:]))
-			if err != nil {
+			if content, err = f.Render.render(f.FileName, content); err != nil {
 				return err
 			}
-			getenvironment := func(data []string, getkeyval func(item string) (key, val string)) map[string]string {
-				items := make(map[string]string)
-				for _, item := range data {
-					key, val := getkeyval(item)
-					items[key] = val
-				}
-				return items
-			}
-			environment := getenvironment(os.Environ(), func(item string) (key, val string) {
-				splits := strings.Split(item, "=")
-				key = splits[0]
-				val = splits[1]
-				return
-			})
-
-			var b bytes.Buffer
-			if err := tmpl.ExecuteTemplate(&b, f.Name, environment); err != nil {
-				return err
-			}
-			content = b.Bytes()
+		}
+		if content, err = f.Render.substitute(content); err != nil {
+			return fmt.Errorf("%s: %w", f.FileName, err)
 		}
 
 		f.Content = content
@@ -138,6 +172,67 @@ func (f *File) ReadContent() error {
 	return nil
 }
 
+// readRaw reads the file from its file system as is.
+func (f *File) readRaw() ([]byte, error) {
+	if f.FS == nil {
+		return ioutil.ReadFile(path.Join(f.Path, f.FileName))
+	}
+	return fs.ReadFile(f.FS, path.Join(f.Path, f.FileName))
+}
+
+// dirFile is a file found in migrations directory.
+type dirFile struct {
+	// dir is the directory containing the file
+	dir  string
+	name string
+	// rel is path to the file relative to migrations directory
+	rel string
+}
+
+// listFiles lists files of dir and, if recursive,
+// of its subdirectories, skipping hidden ones.
+func listFiles(fsys fs.FS, dir string, recursive bool) ([]dirFile, error) {
+	var files []dirFile
+	var walk func(sub, rel string) error
+	walk = func(sub, rel string) error {
+		entries, err := readDir(fsys, sub)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if !e.IsDir() {
+				files = append(files, dirFile{dir: sub, name: e.Name(), rel: path.Join(rel, e.Name())})
+				continue
+			}
+			if recursive && !strings.HasPrefix(e.Name(), ".") {
+				if err := walk(path.Join(sub, e.Name()), path.Join(rel, e.Name())); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(dir, ""); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// readDir lists directory in file system, nil fsys stands for disk.
+func readDir(fsys fs.FS, dir string) ([]fs.DirEntry, error) {
+	if fsys == nil {
+		return os.ReadDir(dir)
+	}
+	return fs.ReadDir(fsys, dir)
+}
+
+// Checksum returns hex-encoded SHA-256 checksum of the file content.
+// Content should be read with ReadContent first.
+func (f *File) Checksum() string {
+	sum := sha256.Sum256(f.Content)
+	return hex.EncodeToString(sum[:])
+}
+
 // IsTemplate returns true if the current file is a Template
 func (f *File) IsTemplate() bool {
 	if filepath.Ext(f.FileName) == ".tpl" {
@@ -158,14 +253,27 @@ func (mf *MigrationFiles) Pending(versions Versions) (Files, error) {
 	return files, nil
 }
 
-// Applied returns the list of applied migration files.
+// Applied returns down files of applied migrations, newest first.
+// It returns *IrreversibleError if any applied migration can't be rolled back.
 func (mf *MigrationFiles) Applied(versions Versions) (Files, error) {
+	return mf.downFiles(versions, len(versions))
+}
+
+// downFiles returns down files of n latest applied migrations.
+func (mf *MigrationFiles) downFiles(versions Versions, n int) (Files, error) {
 	sort.Sort(sort.Reverse(mf))
 	files := make(Files, 0)
 	for _, migrationFile := range *mf {
-		if versions.Contains(migrationFile.Version) && migrationFile.DownFile != nil {
-			files = append(files, *migrationFile.DownFile)
+		if len(files) >= n {
+			break
+		}
+		if !versions.Contains(migrationFile.Version) {
+			continue
+		}
+		if err := migrationFile.CheckReversible(); err != nil {
+			return nil, err
 		}
+		files = append(files, *migrationFile.DownFile)
 	}
 	return files, nil
 }
@@ -194,18 +302,104 @@ func (mf *MigrationFiles) Relative(relativeN int, versions Versions) (Files, err
 		files, err = mf.Pending(versions)
 	} else {
 		relativeN = -relativeN
-		files, err = mf.Applied(versions)
+		files, err = mf.downFiles(versions, relativeN)
+	}
+	if err != nil {
+		return nil, err
 	}
 	if relativeN > len(files) {
 		relativeN = len(files)
 	}
-	return files[:relativeN], err
+	return files[:relativeN], nil
+}
+
+// To returns the list of migration files that bring the database
+// from given applied versions to the target version.
+// Down files for applied versions newer than target come first (newest first),
+// followed by up files for pending versions up to and including target.
+// Target version 0 rolls back all applied migrations.
+func (mf *MigrationFiles) To(target Version, versions Versions) (Files, error) {
+	sort.Sort(mf)
+	byVersion := make(map[Version]MigrationFile, len(*mf))
+	for _, migrationFile := range *mf {
+		byVersion[migrationFile.Version] = migrationFile
+	}
+	if target != 0 {
+		if migrationFile, ok := byVersion[target]; !ok || migrationFile.UpFile == nil {
+			return nil, fmt.Errorf("no up migration file for target version %d", target)
+		}
+	}
+
+	applied := make(Versions, len(versions))
+	copy(applied, versions)
+	sort.Sort(sort.Reverse(applied))
+
+	files := make(Files, 0)
+	for _, version := range applied {
+		if version <= target {
+			break
+		}
+		migrationFile, ok := byVersion[version]
+		if !ok {
+			return nil, fmt.Errorf("no down migration file for version %d", version)
+		}
+		if err := migrationFile.CheckReversible(); err != nil {
+			return nil, err
+		}
+		files = append(files, *migrationFile.DownFile)
+	}
+	for _, migrationFile := range *mf {
+		if migrationFile.Version > target {
+			break
+		}
+		if !versions.Contains(migrationFile.Version) && migrationFile.UpFile != nil {
+			files = append(files, *migrationFile.UpFile)
+		}
+	}
+	return files, nil
+}
+
+// Options configure where ReadMigrationFilesWith and ValidateWith look for
+// migration files and how they render them. Zero value reads given
+// directory on disk.
+type Options struct {
+	// FS is read instead of disk if set, e.g. embedded files or archive.
+	FS fs.FS
+
+	// Recursive also reads subdirectories, skipping hidden ones. Up and
+	// down files of a version may be in different subdirectories,
+	// File.Path is the directory containing the file.
+	Recursive bool
+
+	// Render is set on read files and used to check templates and
+	// placeholders when validating. Nil renders with defaults.
+	Render *RenderOptions
 }
 
 // ReadMigrationFiles reads all migration files from a given path.
 func ReadMigrationFiles(path string, filenameRegex *regexp.Regexp) (files MigrationFiles, err error) {
+	return ReadMigrationFilesWith(path, filenameRegex, Options{})
+}
+
+// ReadMigrationFilesWith is like ReadMigrationFiles, reading files as set in opts.
+func ReadMigrationFilesWith(path string, filenameRegex *regexp.Regexp, opts Options) (files MigrationFiles, err error) {
+	files, err = readMigrationFiles(opts.FS, path, filenameRegex, opts.Recursive)
+	if err != nil || opts.Render == nil {
+		return files, err
+	}
+	for _, mf := range files {
+		for _, f := range []*File{mf.UpFile, mf.DownFile} {
+			if f != nil {
+				f.Render = opts.Render
+			}
+		}
+	}
+	return files, nil
+}
+
+func readMigrationFiles(fsys fs.FS, path string, filenameRegex *regexp.Regexp, recursive bool) (files MigrationFiles, err error) {
 	// find all migration files in path.
-	ioFiles, err := ioutil.ReadDir(path)
+	ioFiles, err := listFiles(fsys, path, recursive)
 	if err != nil {
 		return nil, err
 	}
@@ -214,21 +408,23 @@ func ReadMigrationFiles(path string, filenameRegex *regexp.Regexp) (files Migrat
 		name     string
 		filename string
 		d        direction.Direction
+		dir      string
+		rel      string
 	}
 	var tmpFiles []*tmpFile
 	tmpFileMap := map[Version]map[direction.Direction]tmpFile{}
 	for _, file := range ioFiles {
-		version, name, d, err := parseFilenameSchema(file.Name(), filenameRegex)
+		version, name, d, err := parseFilenameSchema(file.name, filenameRegex)
 		if err == nil {
 			if _, ok := tmpFileMap[version]; !ok {
 				tmpFileMap[version] = map[direction.Direction]tmpFile{}
 			}
 			if existing, ok := tmpFileMap[version][d]; !ok {
-				tmpFileMap[version][d] = tmpFile{version: version, name: name, filename: file.Name(), d: d}
+				tmpFileMap[version][d] = tmpFile{version: version, name: name, filename: file.name, d: d, dir: file.dir, rel: file.rel}
 			} else {
-				return nil, fmt.Errorf("duplicate migration file version %d : %q and %q", version, existing.filename, file.Name())
+				return nil, fmt.Errorf("duplicate migration file version %d : %q and %q", version, existing.rel, file.rel)
 			}
-			tmpFiles = append(tmpFiles, &tmpFile{version, name, file.Name(), d})
+			tmpFiles = append(tmpFiles, &tmpFile{version, name, file.name, d, file.dir, file.rel})
 		}
 	}
 
@@ -245,7 +441,9 @@ func ReadMigrationFiles(path string, filenameRegex *regexp.Regexp) (files Migrat
 			switch file.d {
 			case direction.Up:
 				migrationFile.UpFile = &File{
-					Path:      path,
+					FS:        fsys,
+					Path:      file.dir,
+					Source:    path,
 					FileName:  file.filename,
 					Version:   file.version,
 					Name:      file.name,
@@ -255,7 +453,9 @@ func ReadMigrationFiles(path string, filenameRegex *regexp.Regexp) (files Migrat
 				lookFordirection = direction.Down
 			case direction.Down:
 				migrationFile.DownFile = &File{
-					Path:      path,
+					FS:        fsys,
+					Path:      file.dir,
+					Source:    path,
 					FileName:  file.filename,
 					Version:   file.version,
 					Name:      file.name,
@@ -272,7 +472,9 @@ func ReadMigrationFiles(path string, filenameRegex *regexp.Regexp) (files Migrat
 					switch lookFordirection {
 					case direction.Up:
 						migrationFile.UpFile = &File{
-							Path:      path,
+							FS:        fsys,
+							Path:      file2.dir,
+							Source:    path,
 							FileName:  file2.filename,
 							Version:   file.version,
 							Name:      file2.name,
@@ -281,7 +483,9 @@ func ReadMigrationFiles(path string, filenameRegex *regexp.Regexp) (files Migrat
 						}
 					case direction.Down:
 						migrationFile.DownFile = &File{
-							Path:      path,
+							FS:        fsys,
+							Path:      file2.dir,
+							Source:    path,
 							FileName:  file2.filename,
 							Version:   file.version,
 							Name:      file2.name,
@@ -302,6 +506,34 @@ func ReadMigrationFiles(path string, filenameRegex *regexp.Regexp) (files Migrat
 	return newFiles, nil
 }
 
+// MergeMigrationFiles merges migration files read from several directories
+// into one list ordered by version.
+// It fails if a version is defined in more than one directory.
+func MergeMigrationFiles(sets ...MigrationFiles) (MigrationFiles, error) {
+	seen := map[Version]MigrationFile{}
+	var merged MigrationFiles
+	for _, files := range sets {
+		for _, mf := range files {
+			if existing, ok := seen[mf.Version]; ok {
+				return nil, fmt.Errorf("duplicate migration version %d : %q and %q", mf.Version, existing.path(), mf.path())
+			}
+			seen[mf.Version] = mf
+			merged = append(merged, mf)
+		}
+	}
+	sort.Sort(merged)
+	return merged, nil
+}
+
+// path returns path to up or, if there is none, down file.
+func (mf MigrationFile) path() string {
+	f := mf.UpFile
+	if f == nil {
+		f = mf.DownFile
+	}
+	return path.Join(f.Path, f.FileName)
+}
+
 // parseFilenameSchema parses the filename.
 func parseFilenameSchema(filename string, filenameRegex *regexp.Regexp) (version Version, name string, d direction.Direction, err error) {
 	matches := filenameRegex.FindStringSubmatch(filename)
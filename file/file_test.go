@@ -4,7 +4,11 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"text/template"
 
 	"github.com/db-journey/migrate/v2/direction"
 )
@@ -173,18 +177,29 @@ func TestFiles(t *testing.T) {
 		appliedVersions Versions
 		relative        int
 		expectRange     Versions
+		expectErr       bool
 	}{
-		{Versions{}, 2, Versions{1, 2}},
-		{Versions{1}, 4, Versions{2, 101, 301, 501}},
-		{Versions{1}, 0, nil},
-		{Versions{}, 1, Versions{1}},
-		{Versions{}, 0, nil},
-		{Versions{1, 2, 101}, -2, Versions{101, 2}},
-		{Versions{1, 2, 101, 301, 401, 501}, -1, Versions{401}},
+		{Versions{}, 2, Versions{1, 2}, false},
+		{Versions{1}, 4, Versions{2, 101, 301, 501}, false},
+		{Versions{1}, 0, nil, false},
+		{Versions{}, 1, Versions{1}, false},
+		{Versions{}, 0, nil, false},
+		{Versions{1, 2, 101}, -2, Versions{101, 2}, false},
+		{Versions{1, 2, 101, 401}, -1, Versions{401}, false},
+		// 501 has no down file
+		{Versions{1, 2, 101, 301, 401, 501}, -1, nil, true},
+		{Versions{1, 2, 101, 301}, -1, nil, true},
+		{Versions{1, 2, 101, 301}, -5, nil, true},
 	}
 
 	for _, test := range tests {
 		rangeFiles, err := files.Relative(test.relative, test.appliedVersions)
+		if test.expectErr {
+			if _, ok := err.(*IrreversibleError); !ok {
+				t.Errorf("file.Relative(): expected irreversible error for %v, got %v", test.appliedVersions, err)
+			}
+			continue
+		}
 		if err != nil {
 			t.Error("Unable to fetch range:", err)
 		}
@@ -220,6 +235,79 @@ func TestDuplicateFiles(t *testing.T) {
 	}
 }
 
+func TestTo(t *testing.T) {
+	root, cleanFn, err := makeFiles("TestTo",
+		"001_first.up.sql", "001_first.down.sql",
+		"002_second.up.sql", "002_second.down.sql",
+		"003_third.up.sql",
+		"004_fourth.up.sql", "004_fourth.down.sql",
+	)
+	defer cleanFn()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ReadMigrationFiles(root, FilenameRegex("sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		appliedVersions Versions
+		target          Version
+		expectRange     Versions
+		expectDirection []direction.Direction
+		expectErr       bool
+	}{
+		{Versions{}, 2, Versions{1, 2}, []direction.Direction{direction.Up, direction.Up}, false},
+		{Versions{1}, 4, Versions{2, 3, 4}, []direction.Direction{direction.Up, direction.Up, direction.Up}, false},
+		{Versions{2, 1}, 1, Versions{2}, []direction.Direction{direction.Down}, false},
+		{Versions{2, 1}, 0, Versions{2, 1}, []direction.Direction{direction.Down, direction.Down}, false},
+		{Versions{1, 4}, 2, Versions{4, 2}, []direction.Direction{direction.Down, direction.Up}, false},
+		{Versions{1, 2}, 2, Versions{}, nil, false},
+		{Versions{1, 2, 3}, 1, nil, nil, true}, // no down file for 3
+		{Versions{1, 5}, 1, nil, nil, true},    // 5 is not on disk
+		{Versions{}, 5, nil, nil, true},        // unknown target
+	}
+
+	for _, test := range tests {
+		rangeFiles, err := files.To(test.target, test.appliedVersions)
+		if test.expectErr {
+			if err == nil {
+				t.Fatalf("file.To(%d, %v): expected error, got none", test.target, test.appliedVersions)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("file.To(%d, %v): unexpected error: %s", test.target, test.appliedVersions, err)
+		}
+		if len(rangeFiles) != len(test.expectRange) {
+			t.Fatalf("file.To(%d, %v): expected %v files, got %v", test.target, test.appliedVersions, len(test.expectRange), len(rangeFiles))
+		}
+		for i, version := range test.expectRange {
+			if rangeFiles[i].Version != version || rangeFiles[i].Direction != test.expectDirection[i] {
+				t.Logf("rangeFiles: %v\n", rangeFiles)
+				t.Fatalf("file.To(%d, %v): returned files dont match expectations %v", test.target, test.appliedVersions, test.expectRange)
+			}
+		}
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	f := File{Content: []byte("CREATE TABLE foo;")}
+	same := File{Content: []byte("CREATE TABLE foo;")}
+	other := File{Content: []byte("CREATE TABLE bar;")}
+	if len(f.Checksum()) != 64 {
+		t.Errorf("Expected hex-encoded SHA-256 checksum, got %q", f.Checksum())
+	}
+	if f.Checksum() != same.Checksum() {
+		t.Error("Expected equal checksums for equal content")
+	}
+	if f.Checksum() == other.Checksum() {
+		t.Error("Expected different checksums for different content")
+	}
+}
+
 // makeFiles takes an identifier, and a list of file names and uses them to create a temporary
 // directory populated with files named with the names passed in.  makeFiles returns the root
 // directory name, and a func suitable for a defer cleanup to remove the temporary files after
@@ -242,3 +330,373 @@ func makeFiles(testname string, names ...string) (root string, cleanup func(), e
 	}
 	return
 }
+
+func TestCheckReversible(t *testing.T) {
+	tests := []struct {
+		mf         MigrationFile
+		reversible bool
+	}{
+		{MigrationFile{Version: 1, UpFile: &File{Content: []byte("CREATE TABLE a;")}, DownFile: &File{}}, true},
+		{MigrationFile{Version: 2, UpFile: &File{Content: []byte("CREATE TABLE a;")}}, false},
+		{MigrationFile{Version: 3, UpFile: &File{Content: []byte("-- migrate:irreversible\nDROP TABLE a;")}, DownFile: &File{}}, false},
+		{MigrationFile{Version: 4, UpFile: &File{Content: []byte("CREATE TABLE a;\n-- migrate:irreversible")}, DownFile: &File{}}, true},
+		{MigrationFile{Version: 5, DownFile: &File{}}, true},
+	}
+	for _, test := range tests {
+		err := test.mf.CheckReversible()
+		if test.reversible && err != nil {
+			t.Errorf("Expected migration %d to be reversible, got %v", test.mf.Version, err)
+		}
+		if !test.reversible {
+			if e, ok := err.(*IrreversibleError); !ok || e.Version != test.mf.Version {
+				t.Errorf("Expected migration %d to be irreversible, got %v", test.mf.Version, err)
+			}
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	root, cleanup, err := makeFiles("TestValidate",
+		"001_ok.up.sql",
+		"001_ok.down.sql",
+		"002_renamed.up.sql",
+		"002_old_name.down.sql",
+		"003_down_only.down.sql",
+		"004_upper.UP.sql",
+		"005_text.up.sql.txt",
+		"006_case.up.sql",
+		"006_Case.up.sql",
+		"007_tpl.up.sql.tpl",
+		"README.md",
+	)
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(root, "007_tpl.up.sql.tpl"), []byte("{{ .USER "), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	diags, err := Validate(root, FilenameRegex("sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		kind     DiagnosticKind
+		version  Version
+		filename string
+	}{
+		{UnmatchedFile, 0, "005_text.up.sql.txt"},
+		{NameMismatch, 2, "002_renamed.up.sql"},
+		{MissingUpFile, 3, "003_down_only.down.sql"},
+		{UnmatchedFile, 4, "004_upper.UP.sql"},
+		{DuplicateVersion, 6, "006_Case.up.sql"},
+		{TemplateError, 7, "007_tpl.up.sql.tpl"},
+	}
+	if len(diags) != len(expected) {
+		t.Fatalf("expected %d diagnostics, got %d: %v", len(expected), len(diags), diags)
+	}
+	for i, e := range expected {
+		d := diags[i]
+		if d.Kind != e.kind || d.Version != e.version || d.FileNames[0] != e.filename {
+			t.Errorf("diagnostic %d: expected %s of %d in %s, got %v", i, e.kind, e.version, e.filename, d)
+		}
+	}
+
+	// case variant of version without other files is reported once
+	root2, cleanup2, err := makeFiles("TestValidate", "001_x.up.sql", "001_x.DOWN.sql")
+	defer cleanup2()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diags, err = Validate(root2, FilenameRegex("sql")); err != nil {
+		t.Fatal(err)
+	}
+	if len(diags) != 1 || diags[0].Kind != UnmatchedFile {
+		t.Fatalf("expected only unmatched file, got %v", diags)
+	}
+}
+
+func TestReadMigrationFilesWithFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/001_first.up.sql":    {Data: []byte("-- " + IrreversibleDirective)},
+		"sql/002_second.up.sql":   {Data: []byte("CREATE TABLE second;")},
+		"sql/002_second.down.sql": {Data: []byte("DROP TABLE second;")},
+	}
+	files, err := ReadMigrationFilesWith("sql", FilenameRegex("sql"), Options{FS: fsys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 migration files, got %d", len(files))
+	}
+	if err := files[0].CheckReversible(); err == nil {
+		t.Error("expected first migration to be irreversible")
+	}
+	if err := files[1].DownFile.ReadContent(); err != nil {
+		t.Fatal(err)
+	}
+	if string(files[1].DownFile.Content) != "DROP TABLE second;" {
+		t.Errorf("unexpected content %q", files[1].DownFile.Content)
+	}
+}
+
+func TestMergeMigrationFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"users/001_users.up.sql":      {},
+		"users/003_emails.up.sql":     {},
+		"billing/002_invoices.up.sql": {},
+		"orders/003_orders.down.sql":  {},
+	}
+	read := func(dir string) MigrationFiles {
+		files, err := ReadMigrationFilesWith(dir, FilenameRegex("sql"), Options{FS: fsys})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return files
+	}
+
+	merged, err := MergeMigrationFiles(read("users"), read("billing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sources []string
+	for _, mf := range merged {
+		sources = append(sources, mf.UpFile.Source)
+	}
+	if !reflect.DeepEqual(sources, []string{"users", "billing", "users"}) {
+		t.Errorf("expected files ordered by version, got sources %v", sources)
+	}
+
+	_, err = MergeMigrationFiles(read("users"), read("orders"))
+	if err == nil || !strings.Contains(err.Error(), "users/003_emails.up.sql") || !strings.Contains(err.Error(), "orders/003_orders.down.sql") {
+		t.Errorf("expected duplicate version error naming both files, got %v", err)
+	}
+}
+
+func TestReadMigrationFilesRecursive(t *testing.T) {
+	root, cleanup, err := makeFiles("TestReadMigrationFilesRecursive", "001_first.up.sql")
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"2023/002_second.up.sql", "2024/q1/002_second.down.sql", ".hidden/003_third.up.sql"} {
+		if err := os.MkdirAll(path.Dir(path.Join(root, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path.Join(root, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	flat, err := ReadMigrationFiles(root, FilenameRegex("sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flat) != 1 {
+		t.Fatalf("expected subdirectories to be ignored, got %d migration files", len(flat))
+	}
+
+	files, err := ReadMigrationFilesWith(root, FilenameRegex("sql"), Options{Recursive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 migration files, got %d", len(files))
+	}
+	second := files[1]
+	if second.UpFile == nil || second.DownFile == nil {
+		t.Fatalf("expected up and down files to be paired, got %+v", second)
+	}
+	if second.DownFile.Path != path.Join(root, "2024/q1") || second.DownFile.Source != root {
+		t.Errorf("unexpected down file path %q, source %q", second.DownFile.Path, second.DownFile.Source)
+	}
+	if err := second.DownFile.ReadContent(); err != nil {
+		t.Fatal(err)
+	}
+	if string(second.DownFile.Content) != "2024/q1/002_second.down.sql" {
+		t.Errorf("unexpected content %q", second.DownFile.Content)
+	}
+
+	if err := ioutil.WriteFile(path.Join(root, "2024/002_again.up.sql"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = ReadMigrationFilesWith(root, FilenameRegex("sql"), Options{Recursive: true})
+	if err == nil || !strings.Contains(err.Error(), `"2023/002_second.up.sql" and "2024/002_again.up.sql"`) {
+		t.Errorf("expected duplicate error with relative paths, got %v", err)
+	}
+	diags, err := ValidateWith(root, FilenameRegex("sql"), Options{Recursive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diags) != 1 || diags[0].Kind != DuplicateVersion || diags[0].FileNames[1] != "2024/002_again.up.sql" {
+		t.Errorf("expected duplicate version diagnostic, got %v", diags)
+	}
+}
+
+func TestReadContentTemplate(t *testing.T) {
+	os.Setenv("MIGRATE_TEST_DSN", "user=me password=a=b")
+	defer os.Unsetenv("MIGRATE_TEST_DSN")
+	os.Unsetenv("MIGRATE_TEST_UNSET")
+
+	tests := []struct {
+		content   string
+		render    *RenderOptions
+		expected  string
+		expectErr bool
+	}{
+		{`{{ .MIGRATE_TEST_DSN }}`, nil, "user=me password=a=b", false},
+		{`{{ env "MIGRATE_TEST_DSN" }}`, nil, "user=me password=a=b", false},
+		{`{{ env "MIGRATE_TEST_UNSET" "public" }}`, nil, "public", false},
+		{`{{ env "MIGRATE_TEST_UNSET" }}`, nil, "", false},
+		{`{{ requiredEnv "MIGRATE_TEST_UNSET" }}`, nil, "", true},
+		{`{{ .Schema }}.{{ upper "t" }}`, &RenderOptions{
+			Data:  map[string]string{"Schema": "tenant_1"},
+			Funcs: template.FuncMap{"upper": strings.ToUpper},
+		}, "tenant_1.T", false},
+		{`{{ env "MIGRATE_TEST_DSN" }}`, &RenderOptions{
+			Funcs: template.FuncMap{"env": func(string) string { return "overridden" }},
+		}, "overridden", false},
+		{`{{ upper "t" }}`, nil, "", true},
+	}
+	for i, test := range tests {
+		f := &File{
+			FS:       fstest.MapFS{"001_t.up.sql.tpl": {Data: []byte(test.content)}},
+			Path:     ".",
+			FileName: "001_t.up.sql.tpl",
+			Render:   test.render,
+		}
+		err := f.ReadContent()
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("test %d: expected error, got content %q", i, f.Content)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: %v", i, err)
+			continue
+		}
+		if string(f.Content) != test.expected {
+			t.Errorf("test %d: expected %q, got %q", i, test.expected, f.Content)
+		}
+	}
+}
+
+func TestReadContentPartials(t *testing.T) {
+	os.Unsetenv("MIGRATE_TEST_UNSET")
+	fsys := fstest.MapFS{
+		"partials/grants.sql.tpl":    {Data: []byte(`GRANT SELECT ON {{ .Table }} TO {{ .Role }};`)},
+		"partials/triggers.sql.tpl":  {Data: []byte(`{{ define "audit" }}CREATE TRIGGER audit_{{ .Table }};{{ end }}`)},
+		"partials/broken.sql.tpl":    {Data: []byte("-- ok\n{{ requiredEnv \"MIGRATE_TEST_UNSET\" }}")},
+		"partials/.hidden.tpl":       {Data: []byte(`{{ oops`)},
+		"001_t.up.sql.tpl":           {Data: []byte(`{{ template "grants" . }} {{ template "audit" . }}`)},
+		"002_t.up.sql.tpl":           {Data: []byte(`{{ template "broken" . }}`)},
+		"invalid/broken.sql.tpl":     {Data: []byte("\n\n{{ if }}")},
+		"duplicate/grants.sql.tpl":   {Data: nil},
+		"duplicate/grants.mysql.tpl": {Data: nil},
+	}
+	render := &RenderOptions{
+		Data:        map[string]string{"Table": "users", "Role": "reader"},
+		PartialsFS:  fsys,
+		PartialsDir: "partials",
+	}
+	f := &File{FS: fsys, Path: ".", FileName: "001_t.up.sql.tpl", Render: render}
+	if err := f.ReadContent(); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "GRANT SELECT ON users TO reader; CREATE TRIGGER audit_users;"; string(f.Content) != expected {
+		t.Errorf("expected %q, got %q", expected, f.Content)
+	}
+
+	f = &File{FS: fsys, Path: ".", FileName: "002_t.up.sql.tpl", Render: render}
+	if err := f.ReadContent(); err == nil || !strings.Contains(err.Error(), "partials/broken.sql.tpl:2:") {
+		t.Errorf("expected error naming partial file and line, got %v", err)
+	}
+
+	for dir, expected := range map[string]string{
+		"invalid":   "invalid/broken.sql.tpl:3:",
+		"duplicate": `same name "grants"`,
+		"missing":   "reading partials",
+	} {
+		render.PartialsDir = dir
+		f = &File{FS: fsys, Path: ".", FileName: "001_t.up.sql.tpl", Render: render}
+		if err := f.ReadContent(); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected error containing %q, got %v", dir, expected, err)
+		}
+	}
+}
+
+func TestReadContentPlaceholders(t *testing.T) {
+	values := map[string]string{"schema": "tenant_1", "owner": "app"}
+	tests := []struct {
+		filename     string
+		content      string
+		placeholders map[string]string
+		expected     string
+		expectErr    string
+	}{
+		{"001_t.up.sql", "CREATE TABLE ${schema}.t;\nALTER TABLE ${schema}.t OWNER TO ${owner};", values,
+			"CREATE TABLE tenant_1.t;\nALTER TABLE tenant_1.t OWNER TO app;", ""},
+		{"001_t.up.sql", `SELECT '{"a": "$${literal}"}', '$5', '{{ x }}';`, values,
+			`SELECT '{"a": "${literal}"}', '$5', '{{ x }}';`, ""},
+		{"001_t.up.sql", "CREATE TABLE ${schema}.t;", nil, "CREATE TABLE ${schema}.t;", ""},
+		{"001_t.up.sql.tpl", `CREATE TABLE ${schema}.{{ "t" }};`, values, "CREATE TABLE tenant_1.t;", ""},
+		{"001_t.up.sql", "SELECT 1;\nSELECT ${nope};", values, "", "001_t.up.sql: line 2: unknown placeholder ${nope}"},
+		{"001_t.up.sql", "SELECT ${schema\n};", values, "", "001_t.up.sql: line 1: unterminated placeholder"},
+	}
+	for i, test := range tests {
+		f := &File{
+			FS:       fstest.MapFS{test.filename: {Data: []byte(test.content)}},
+			Path:     ".",
+			FileName: test.filename,
+			Render:   &RenderOptions{Placeholders: test.placeholders},
+		}
+		err := f.ReadContent()
+		if test.expectErr != "" {
+			if err == nil || err.Error() != test.expectErr {
+				t.Errorf("test %d: expected error %q, got %v", i, test.expectErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: %v", i, err)
+			continue
+		}
+		if string(f.Content) != test.expected {
+			t.Errorf("test %d: expected %q, got %q", i, test.expected, f.Content)
+		}
+	}
+}
+
+func TestValidatePlaceholders(t *testing.T) {
+	fsys := fstest.MapFS{
+		"001_ok.up.sql":         {Data: []byte("CREATE TABLE ${schema}.t;")},
+		"001_ok.down.sql":       {Data: []byte("DROP TABLE $${schema}.t;")},
+		"002_typo.up.sql":       {Data: []byte("SELECT 1;\nCREATE TABLE ${shema}.t;")},
+		"003_open.up.sql.tpl":   {Data: []byte("CREATE TABLE {{ \"t\" }} (${schema\n);")},
+		"004_tpl_ok.up.sql.tpl": {Data: []byte("CREATE TABLE ${schema}.{{ \"t\" }};")},
+	}
+	render := &RenderOptions{Placeholders: map[string]string{"schema": "tenant_1"}}
+	diags, err := ValidateWith(".", FilenameRegex("sql"), Options{FS: fsys, Render: render})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"placeholder-error: 002_typo.up.sql: line 2: unknown placeholder ${shema}",
+		"placeholder-error: 003_open.up.sql.tpl: line 1: unterminated placeholder",
+	}
+	if len(diags) != len(expected) {
+		t.Fatalf("expected %d diagnostics, got %v", len(expected), diags)
+	}
+	for i, e := range expected {
+		if diags[i].String() != e {
+			t.Errorf("diagnostic %d: expected %q, got %q", i, e, diags[i])
+		}
+	}
+
+	// placeholders aren't checked unless enabled
+	if diags, err = ValidateWith(".", FilenameRegex("sql"), Options{FS: fsys}); err != nil || len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %v, %v", diags, err)
+	}
+}
@@ -0,0 +1,180 @@
+package file
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// RenderOptions configure how ReadContent renders templates (.tpl files).
+type RenderOptions struct {
+	// Data is passed to templates, map of environment variables if nil.
+	Data interface{}
+
+	// Funcs are added to templates, overriding built in functions:
+	//
+	//	env "NAME" "default"  value of environment variable, default is optional
+	//	requiredEnv "NAME"    value of environment variable, fails if it's unset
+	Funcs template.FuncMap
+
+	// PartialsDir is directory of templates parsed with each migration
+	// template, read from PartialsFS or disk if it's nil. Partial is named
+	// after its file name up to the first dot, e.g. {{ template "grants" . }}
+	// executes grants.sql.tpl. Hidden files and subdirectories are skipped.
+	PartialsFS  fs.FS
+	PartialsDir string
+
+	// Placeholders enable substitution of ${name} in all migration files,
+	// after templates are rendered. Unknown placeholder is an error,
+	// $${ is written as literal ${. Nil disables substitution.
+	Placeholders map[string]string
+}
+
+var builtinFuncs = template.FuncMap{
+	"env": func(name string, def ...string) (string, error) {
+		if len(def) > 1 {
+			return "", fmt.Errorf("env: expected at most one default value for %s, got %d", name, len(def))
+		}
+		if val, ok := os.LookupEnv(name); ok {
+			return val, nil
+		}
+		if len(def) == 1 {
+			return def[0], nil
+		}
+		return "", nil
+	},
+	"requiredEnv": func(name string) (string, error) {
+		if val, ok := os.LookupEnv(name); ok {
+			return val, nil
+		}
+		return "", fmt.Errorf("required environment variable %s is not set", name)
+	},
+}
+
+// parse parses template with built in and configured functions and partials.
+func (r *RenderOptions) parse(name string, content []byte) (*template.Template, error) {
+	tmpl := template.New(name).Funcs(builtinFuncs)
+	if r != nil {
+		tmpl = tmpl.Funcs(r.Funcs)
+		if r.PartialsDir != "" {
+			if err := r.parsePartials(tmpl); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return tmpl.Parse(string(content))
+}
+
+// parsePartials adds partials to tmpl. Partials are parsed under their paths,
+// so errors name the partial file and line, and added under their short names.
+func (r *RenderOptions) parsePartials(tmpl *template.Template) error {
+	entries, err := readDir(r.PartialsFS, r.PartialsDir)
+	if err != nil {
+		return fmt.Errorf("reading partials: %w", err)
+	}
+	files := map[string]string{}
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		name := strings.SplitN(e.Name(), ".", 2)[0]
+		if existing, ok := files[name]; ok {
+			return fmt.Errorf("partials %s and %s have the same name %q", existing, e.Name(), name)
+		}
+		files[name] = e.Name()
+
+		f := &File{FS: r.PartialsFS, Path: r.PartialsDir, FileName: e.Name()}
+		content, err := f.readRaw()
+		if err != nil {
+			return err
+		}
+		partial, err := tmpl.New(path.Join(r.PartialsDir, e.Name())).Parse(string(content))
+		if err != nil {
+			return err
+		}
+		if partial.Tree != nil {
+			if _, err := tmpl.AddParseTree(name, partial.Tree); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// render executes template with configured data.
+func (r *RenderOptions) render(name string, content []byte) ([]byte, error) {
+	tmpl, err := r.parse(name, content)
+	if err != nil {
+		return nil, err
+	}
+	var data interface{}
+	if r != nil && r.Data != nil {
+		data = r.Data
+	} else {
+		data = environment()
+	}
+	var b bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&b, name, data); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// environment returns environment variables as a map.
+func environment() map[string]string {
+	env := make(map[string]string)
+	for _, item := range os.Environ() {
+		// values may contain "=", names can't
+		splits := strings.SplitN(item, "=", 2)
+		if len(splits) == 2 {
+			env[splits[0]] = splits[1]
+		}
+	}
+	return env
+}
+
+// CheckPlaceholders reports unknown or unterminated placeholders in the file,
+// reading its content like ReadContent. It does nothing if substitution
+// of placeholders isn't enabled in Render.
+func (f *File) CheckPlaceholders() error {
+	if f.Render == nil || f.Render.Placeholders == nil {
+		return nil
+	}
+	return f.ReadContent()
+}
+
+// substitute replaces placeholders in content if they are enabled.
+func (r *RenderOptions) substitute(content []byte) ([]byte, error) {
+	if r == nil || r.Placeholders == nil {
+		return content, nil
+	}
+	var b bytes.Buffer
+	for i := 0; i < len(content); {
+		switch {
+		case bytes.HasPrefix(content[i:], []byte("$${")):
+			b.WriteString("${")
+			i += 3
+		case bytes.HasPrefix(content[i:], []byte("${")):
+			line := bytes.Count(content[:i], []byte("\n")) + 1
+			end := bytes.IndexAny(content[i:], "}\n")
+			if end < 0 || content[i+end] != '}' {
+				return nil, fmt.Errorf("line %d: unterminated placeholder", line)
+			}
+			name := string(content[i+2 : i+end])
+			value, ok := r.Placeholders[name]
+			if !ok {
+				return nil, fmt.Errorf("line %d: unknown placeholder ${%s}", line, name)
+			}
+			b.WriteString(value)
+			i += end + 1
+		default:
+			b.WriteByte(content[i])
+			i++
+		}
+	}
+	return b.Bytes(), nil
+}
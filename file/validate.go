@@ -0,0 +1,225 @@
+package file
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/db-journey/migrate/v2/direction"
+)
+
+// DiagnosticKind classifies problems found by Validate.
+type DiagnosticKind string
+
+// Kinds of problems reported by Validate.
+const (
+	// UnmatchedFile is a file that looks like a migration,
+	// but doesn't match the filename schema, so it's ignored.
+	UnmatchedFile DiagnosticKind = "unmatched-file"
+	// NameMismatch is a version whose up and down files have different names.
+	NameMismatch DiagnosticKind = "name-mismatch"
+	// MissingUpFile is a version with only a down file.
+	MissingUpFile DiagnosticKind = "missing-up-file"
+	// TemplateError is a .tpl file whose template fails to parse.
+	TemplateError DiagnosticKind = "template-error"
+	// PlaceholderError is a file with unknown or unterminated placeholder,
+	// reported if placeholders are enabled in RenderOptions. Only placeholders
+	// written literally in templates are checked.
+	PlaceholderError DiagnosticKind = "placeholder-error"
+	// DuplicateVersion is a version with more than one file
+	// for the same direction, including files differing only in case.
+	DuplicateVersion DiagnosticKind = "duplicate-version"
+)
+
+// Diagnostic describes a single problem found in migrations directory.
+type Diagnostic struct {
+	Kind DiagnosticKind
+	// Version is 0 when the problem isn't tied to a version.
+	Version Version
+	// Source is the checked directory FileNames are in.
+	// It's empty for problems spanning several directories,
+	// FileNames are then paths to files.
+	Source    string
+	FileNames []string
+	Message   string
+}
+
+func (d Diagnostic) String() string {
+	paths := make([]string, 0, len(d.FileNames))
+	for _, name := range d.FileNames {
+		paths = append(paths, path.Join(d.Source, name))
+	}
+	return fmt.Sprintf("%s: %s: %s", d.Kind, strings.Join(paths, ", "), d.Message)
+}
+
+// looksLikeMigration matches file names that were probably meant to be migrations:
+// starting with a version or having a direction in the name.
+var looksLikeMigration = regexp.MustCompile(`(?i)^[0-9]+_|\.(up|down)\.`)
+
+// validatedFile is a file that matches filename schema,
+// possibly in different case.
+type validatedFile struct {
+	name     string
+	filename string
+	// variant matches filename schema only in lower case,
+	// so it's ignored by ReadMigrationFiles.
+	variant bool
+}
+
+// Validate statically checks migration files in the given path
+// and returns all problems found, ordered by version.
+// Problems with files are reported as diagnostics,
+// error is returned only if directory or files can't be read.
+func Validate(path string, filenameRegex *regexp.Regexp) ([]Diagnostic, error) {
+	return ValidateWith(path, filenameRegex, Options{})
+}
+
+// ValidateWith is like Validate, checking files as ReadMigrationFilesWith
+// reads them with opts. Templates are parsed with functions set in
+// opts.Render and placeholders are checked if it enables them.
+// FileNames of diagnostics are relative to path.
+func ValidateWith(path string, filenameRegex *regexp.Regexp, opts Options) ([]Diagnostic, error) {
+	fsys, render := opts.FS, opts.Render
+	ioFiles, err := listFiles(fsys, path, opts.Recursive)
+	if err != nil {
+		return nil, err
+	}
+
+	var diags []Diagnostic
+	parsed := map[Version]map[direction.Direction][]validatedFile{}
+	for _, f := range ioFiles {
+		if strings.HasPrefix(f.name, ".") {
+			continue
+		}
+		version, name, d, err := parseFilenameSchema(f.name, filenameRegex)
+		variant := false
+		if err != nil {
+			if !looksLikeMigration.MatchString(f.name) {
+				continue
+			}
+			diag := Diagnostic{
+				Kind:      UnmatchedFile,
+				FileNames: []string{f.rel},
+				Message:   "doesn't match migration filename schema, file is ignored",
+			}
+			// a case variant still collides with its version
+			// on case insensitive file systems
+			version, name, d, err = parseFilenameSchema(strings.ToLower(f.name), filenameRegex)
+			if err != nil {
+				diags = append(diags, diag)
+				continue
+			}
+			diag.Version = version
+			diag.Message = "differs from migration filename schema in case, file is ignored"
+			diags = append(diags, diag)
+			variant = true
+		} else {
+			fileDiags, err := checkContent(fsys, f, version, render)
+			if err != nil {
+				return nil, err
+			}
+			diags = append(diags, fileDiags...)
+		}
+
+		if parsed[version] == nil {
+			parsed[version] = map[direction.Direction][]validatedFile{}
+		}
+		parsed[version][d] = append(parsed[version][d], validatedFile{name: name, filename: f.rel, variant: variant})
+	}
+
+	for version, byDirection := range parsed {
+		up, down := byDirection[direction.Up], byDirection[direction.Down]
+		for _, files := range [][]validatedFile{up, down} {
+			if len(files) > 1 {
+				names := make([]string, 0, len(files))
+				for _, f := range files {
+					names = append(names, f.filename)
+				}
+				diags = append(diags, Diagnostic{
+					Kind:      DuplicateVersion,
+					Version:   version,
+					FileNames: names,
+					Message:   fmt.Sprintf("%d files for version %d in the same direction", len(files), version),
+				})
+			}
+		}
+
+		up, down = exactFiles(up), exactFiles(down)
+		switch {
+		case len(up) == 0 && len(down) > 0:
+			diags = append(diags, Diagnostic{
+				Kind:      MissingUpFile,
+				Version:   version,
+				FileNames: []string{down[0].filename},
+				Message:   fmt.Sprintf("version %d has only a down file", version),
+			})
+		case len(up) > 0 && len(down) > 0 && up[0].name != down[0].name:
+			diags = append(diags, Diagnostic{
+				Kind:      NameMismatch,
+				Version:   version,
+				FileNames: []string{up[0].filename, down[0].filename},
+				Message:   fmt.Sprintf("up and down files of version %d have different names %q and %q", version, up[0].name, down[0].name),
+			})
+		}
+	}
+
+	for i := range diags {
+		diags[i].Source = path
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		if diags[i].Version != diags[j].Version {
+			return diags[i].Version < diags[j].Version
+		}
+		if diags[i].FileNames[0] != diags[j].FileNames[0] {
+			return diags[i].FileNames[0] < diags[j].FileNames[0]
+		}
+		return diags[i].Kind < diags[j].Kind
+	})
+	return diags, nil
+}
+
+// checkContent parses template and checks placeholders of a migration file.
+func checkContent(fsys fs.FS, f dirFile, version Version, render *RenderOptions) ([]Diagnostic, error) {
+	isTemplate := strings.HasSuffix(f.name, ".tpl")
+	if !isTemplate && (render == nil || render.Placeholders == nil) {
+		return nil, nil
+	}
+	content, err := (&File{FS: fsys, Path: f.dir, FileName: f.name}).readRaw()
+	if err != nil {
+		return nil, err
+	}
+	var diags []Diagnostic
+	if isTemplate {
+		if _, err := render.parse(f.name, content); err != nil {
+			diags = append(diags, Diagnostic{
+				Kind:      TemplateError,
+				Version:   version,
+				FileNames: []string{f.rel},
+				Message:   err.Error(),
+			})
+		}
+	}
+	if _, err := render.substitute(content); err != nil {
+		diags = append(diags, Diagnostic{
+			Kind:      PlaceholderError,
+			Version:   version,
+			FileNames: []string{f.rel},
+			Message:   err.Error(),
+		})
+	}
+	return diags, nil
+}
+
+// exactFiles filters out case variants.
+func exactFiles(files []validatedFile) []validatedFile {
+	var exact []validatedFile
+	for _, f := range files {
+		if !f.variant {
+			exact = append(exact, f)
+		}
+	}
+	return exact
+}
@@ -1,6 +1,6 @@
 module github.com/db-journey/migrate/v2
 
-go 1.13
+go 1.16
 
 require (
 	github.com/go-sql-driver/mysql v1.4.1
@@ -0,0 +1,46 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"os/user"
+
+	"github.com/db-journey/migrate/v2/driver"
+)
+
+// WithAppliedBy sets the value recorded by drivers as the one who applied
+// migrations (see driver.Operator). Defaults to the current OS user name.
+func WithAppliedBy(appliedBy string) Option {
+	return func(h *Handle) error {
+		h.appliedBy = appliedBy
+		return nil
+	}
+}
+
+// History returns records of applied migrations, newest version first.
+// Driver must implement driver.Recorder.
+func (m *Handle) History(ctx context.Context) ([]driver.Record, error) {
+	ctx, unlock, err := m.rlock(ctx, "History")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return m.records()
+}
+
+func (m *Handle) records() ([]driver.Record, error) {
+	recorder, ok := m.drv.(driver.Recorder)
+	if !ok {
+		return nil, fmt.Errorf("driver %T doesn't keep records of applied migrations", m.drv)
+	}
+	return recorder.Records()
+}
+
+// currentUser returns name of the current OS user, if it's known.
+func currentUser() string {
+	u, err := user.Current()
+	if err != nil {
+		return ""
+	}
+	return u.Username
+}
@@ -0,0 +1,37 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	ctx := context.Background()
+	m, _, cleanup := newMockHandle(t, map[string]string{
+		"001_first.up.sql":  "CREATE TABLE first;",
+		"002_second.up.sql": "CREATE TABLE second;",
+	}, WithAppliedBy("deployer"))
+	defer cleanup()
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	history, err := m.History(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("Expected 2 records, got %+v", history)
+	}
+	for i, name := range []string{"second", "first"} {
+		if history[i].Name != name {
+			t.Errorf("Expected record %d to be for migration %q, got %q", i, name, history[i].Name)
+		}
+		if history[i].AppliedBy != "deployer" {
+			t.Errorf("Expected record %d to be applied by %q, got %q", i, "deployer", history[i].AppliedBy)
+		}
+		if history[i].AppliedAt.IsZero() {
+			t.Errorf("Expected record %d to have applied at time", i)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package migrate
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/db-journey/migrate/v2/file"
+)
+
+func TestIrreversible(t *testing.T) {
+	ctx := context.Background()
+	m, drv, cleanup := newMockHandle(t, map[string]string{
+		"001_first.up.sql":    "CREATE TABLE first;",
+		"001_first.down.sql":  "DROP TABLE first;",
+		"002_second.up.sql":   "-- migrate:irreversible\nDROP TABLE legacy;",
+		"002_second.down.sql": "",
+		"003_third.up.sql":    "CREATE TABLE third;",
+		"004_fourth.up.sql":   "CREATE TABLE fourth;",
+		"004_fourth.down.sql": "DROP TABLE fourth;",
+	})
+	defer cleanup()
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// version 4 can be rolled back
+	if err := m.Redo(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, down := range map[string]func() error{
+		"Down":            func() error { return m.Down(ctx) },
+		"Migrate(-2)":     func() error { return m.Migrate(ctx, -2) },
+		"Reset":           func() error { return m.Reset(ctx) },
+		"MigrateTo":       func() error { return m.MigrateTo(ctx, 2) },
+		"RollbackVersion": func() error { return m.RollbackVersion(ctx, 2) },
+	} {
+		err := down()
+		e, ok := err.(*file.IrreversibleError)
+		if !ok {
+			t.Errorf("%s: expected irreversible error, got %v", name, err)
+			continue
+		}
+		if e.Version != 3 && !(name == "RollbackVersion" && e.Version == 2) {
+			t.Errorf("%s: unexpected irreversible version %d", name, e.Version)
+		}
+	}
+	if !reflect.DeepEqual(drv.versions, file.Versions{4, 3, 2, 1}) {
+		t.Errorf("Expected all versions to stay applied, got %v", drv.versions)
+	}
+}
@@ -0,0 +1,135 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/db-journey/migrate/v2/driver"
+)
+
+// Backoff returns delay before given attempt (starting from 2)
+// to acquire database lock, after the previous one failed.
+type Backoff func(attempt int) time.Duration
+
+// ConstantBackoff waits the same delay between attempts.
+func ConstantBackoff(delay time.Duration) Backoff {
+	return func(int) time.Duration {
+		return delay
+	}
+}
+
+// ExponentialBackoff doubles delay after each attempt, starting with initial
+// and never exceeding max.
+func ExponentialBackoff(initial, max time.Duration) Backoff {
+	return func(attempt int) time.Duration {
+		delay := initial
+		for i := 2; i < attempt && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			return max
+		}
+		return delay
+	}
+}
+
+// WithLockTimeout limits time spent waiting for database lock,
+// zero means waiting until context is done.
+// Attempts to lock which fail with error are retried after delay
+// returned by backoff, unless it's nil.
+func WithLockTimeout(timeout time.Duration, backoff Backoff) Option {
+	return func(h *Handle) error {
+		if timeout < 0 {
+			return errors.New("lock timeout can't be negative")
+		}
+		h.lockTimeout = timeout
+		h.lockBackoff = backoff
+		return nil
+	}
+}
+
+// acquireLock locks database for operation op, retrying failed attempts
+// according to backoff policy until lock timeout elapses or ctx is done.
+// Operations of this Handle wait for each other before locking database.
+func (m *Handle) acquireLock(ctx context.Context, op string) error {
+	start := time.Now()
+	if m.lockTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.lockTimeout)
+		defer cancel()
+	}
+	select {
+	case m.sem <- struct{}{}:
+	case <-ctx.Done():
+		return m.lockWaitErr(op, start, 0, ctx.Err())
+	}
+	if err := m.acquireDrvLock(ctx, op, start); err != nil {
+		<-m.sem
+		return err
+	}
+	return nil
+}
+
+// acquireDrvLock locks database, while holding sem.
+func (m *Handle) acquireDrvLock(ctx context.Context, op string, start time.Time) error {
+	// lock attempt abandoned by previous call may still be in progress
+	if m.abandonedLock != nil {
+		select {
+		case <-m.abandonedLock:
+			m.abandonedLock = nil
+		case <-ctx.Done():
+			return m.lockWaitErr(op, start, 0, ctx.Err())
+		}
+	}
+	for attempt := 1; ; attempt++ {
+		err := m.tryLock(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return m.lockWaitErr(op, start, attempt, ctx.Err())
+		}
+		if m.lockBackoff == nil {
+			return err
+		}
+		select {
+		case <-time.After(m.lockBackoff(attempt + 1)):
+		case <-ctx.Done():
+			return m.lockWaitErr(op, start, attempt, fmt.Errorf("%w, last attempt failed with: %s", ctx.Err(), err))
+		}
+	}
+}
+
+// tryLock makes single attempt to lock database, giving up when ctx is done.
+// Lock acquired after giving up is released in background.
+func (m *Handle) tryLock(ctx context.Context) error {
+	locked := make(chan error, 1)
+	go func() {
+		locked <- driver.Lock(m.drv)
+	}()
+	select {
+	case err := <-locked:
+		return err
+	case <-ctx.Done():
+		abandoned := make(chan struct{})
+		m.abandonedLock = abandoned
+		go func() {
+			defer close(abandoned)
+			if err := <-locked; err == nil {
+				driver.Unlock(m.drv) // nothing to do if it fails, connection will be closed eventually
+			}
+		}()
+		return ctx.Err()
+	}
+}
+
+func (m *Handle) lockWaitErr(op string, start time.Time, attempts int, err error) error {
+	who := op
+	if m.appliedBy != "" {
+		who += " by " + m.appliedBy
+	}
+	return fmt.Errorf("%s gave up waiting for database lock after %s (%d attempts): %w",
+		who, time.Since(start).Round(time.Millisecond), attempts, err)
+}
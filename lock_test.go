@@ -0,0 +1,254 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/db-journey/migrate/v2/file"
+)
+
+// lockableMockDriver is mockDriver with a lock.
+// Attempts to lock fail while failures is positive,
+// then block until block channel is closed (if set).
+type lockableMockDriver struct {
+	mockDriver
+	lockMu   sync.Mutex
+	block    chan struct{}
+	failures int
+	attempts int
+	locked   bool
+	unlocked int
+}
+
+func (d *lockableMockDriver) Lock() error {
+	d.lockMu.Lock()
+	d.attempts++
+	if d.failures > 0 {
+		d.failures--
+		d.lockMu.Unlock()
+		return errors.New("lock wait timeout exceeded")
+	}
+	block := d.block
+	d.lockMu.Unlock()
+	if block != nil {
+		<-block
+	}
+	d.lockMu.Lock()
+	defer d.lockMu.Unlock()
+	d.locked = true
+	return nil
+}
+
+func (d *lockableMockDriver) Unlock() error {
+	d.lockMu.Lock()
+	defer d.lockMu.Unlock()
+	d.locked = false
+	d.unlocked++
+	return nil
+}
+
+func TestLockTimeout(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("/tmp", "migrate-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	drv := &lockableMockDriver{block: make(chan struct{})}
+	m, err := New(drv, tmpdir, WithLockTimeout(50*time.Millisecond, nil), WithAppliedBy("deployer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.Up(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected lock timeout, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "Up by deployer gave up waiting") {
+		t.Errorf("Expected error to say who was waiting, got %q", err)
+	}
+
+	// late acquired lock is released
+	close(drv.block)
+	deadline := time.Now().Add(time.Second)
+	for {
+		drv.lockMu.Lock()
+		unlocked := drv.unlocked
+		drv.lockMu.Unlock()
+		if unlocked == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected late acquired lock to be released")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if drv.locked {
+		t.Error("Expected lock to be released")
+	}
+}
+
+func TestLockRetry(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("/tmp", "migrate-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	drv := &lockableMockDriver{failures: 2}
+	m, err := New(drv, tmpdir, WithLockTimeout(time.Second, ConstantBackoff(time.Millisecond)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if drv.attempts != 3 {
+		t.Errorf("Expected 3 attempts to lock, got %d", drv.attempts)
+	}
+
+	drv.failures = 1
+	m, err = New(drv, tmpdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(context.Background()); err == nil {
+		t.Error("Expected failed attempt to lock not to be retried without backoff")
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(10*time.Millisecond, 50*time.Millisecond)
+	for attempt, expected := range map[int]time.Duration{
+		2: 10 * time.Millisecond,
+		3: 20 * time.Millisecond,
+		4: 40 * time.Millisecond,
+		5: 50 * time.Millisecond,
+		9: 50 * time.Millisecond,
+	} {
+		if delay := backoff(attempt); delay != expected {
+			t.Errorf("Expected delay %s before attempt %d, got %s", expected, attempt, delay)
+		}
+	}
+}
+
+func TestConcurrentUse(t *testing.T) {
+	m, drv, cleanup := newMockHandle(t, map[string]string{
+		"001_first.up.sql":  "CREATE TABLE first;",
+		"002_second.up.sql": "BLOCK",
+	})
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	upDone := make(chan error)
+	go func() {
+		upDone <- m.Up(ctx)
+	}()
+
+	// read only queries don't wait for running migration,
+	// since mockDriver isn't Lockable
+	deadline := time.Now().Add(time.Second)
+	for {
+		version, err := m.Version(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected version 1 to be applied while version 2 is migrating")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// other operations changing database wait
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer waitCancel()
+	if err := m.Migrate(waitCtx, -1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected concurrent migration to wait for lock, got %v", err)
+	}
+
+	cancel()
+	if err := <-upDone; err != context.Canceled {
+		t.Errorf("Expected migration to be cancelled, got %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := m.Versions(context.Background()); err != nil {
+				t.Error(err)
+			}
+			if err := m.MigrateTo(context.Background(), 1); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	versions, _ := drv.Versions()
+	if len(versions) != 1 {
+		t.Errorf("Expected single version to stay applied, got %v", versions)
+	}
+}
+
+func TestHookNestedCall(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("/tmp", "migrate-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	for _, name := range []string{"001_first.up.sql", "002_second.up.sql"} {
+		if err := ioutil.WriteFile(path.Join(tmpdir, name), []byte("CREATE TABLE t;"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var m *Handle
+	var versions []file.Version
+	// hooks get no context, read only methods share the lock with them
+	hook := func(f file.File) error {
+		version, err := m.Version(context.Background())
+		versions = append(versions, version)
+		return err
+	}
+	m, err = New(&lockableMockDriver{}, tmpdir, WithHooks(hook, hook))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- m.Up(context.Background())
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Hook calling Handle method blocked on the lock")
+	}
+	if expected := []file.Version{0, 1, 1, 2}; !reflect.DeepEqual(versions, expected) {
+		t.Errorf("Expected versions %v, got %v", expected, versions)
+	}
+
+	// outside of hooks read only methods lock again
+	if _, err := m.Version(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
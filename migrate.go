@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"io/fs"
+	"os"
 	"path"
-	"strconv"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/db-journey/migrate/v2/direction"
@@ -19,6 +21,9 @@ import (
 type Option func(h *Handle) error
 
 // WithHooks allows to add pre/post migration hooks.
+// Hooks run while database lock is held. Read only methods, like Version,
+// called by hooks share the lock, methods changing database wait for
+// the running operation to finish.
 func WithHooks(pre, post func(f file.File) error) Option {
 	return func(h *Handle) error {
 		h.preHook = pre
@@ -27,14 +32,80 @@ func WithHooks(pre, post func(f file.File) error) Option {
 	}
 }
 
-// Handle encapsulates migrations functionality
+// WithSingleTransaction makes operations changing database run all their
+// migrations in single transaction, which commits or rolls back as a unit.
+// Driver must implement driver.Transactional. Observers are notified about
+// successful migrations before transaction is committed.
+func WithSingleTransaction() Option {
+	return func(h *Handle) error {
+		h.singleTx = true
+		return nil
+	}
+}
+
+// WithDryRun makes Handle only pretend to apply migrations.
+// Files that would be applied are rendered and appended to plan
+// in order of execution, Driver.Migrate is never called.
+// Every operation plans from versions applied in database,
+// Version and Versions report versions simulated by the last one.
+// Force is refused in dry run mode.
+func WithDryRun(plan *file.Files) Option {
+	return func(h *Handle) error {
+		if plan == nil {
+			return errors.New("dry run plan can't be nil")
+		}
+		h.dryRun = plan
+		return nil
+	}
+}
+
+// Handle encapsulates migrations functionality.
+// It's safe for concurrent use, operations changing database wait for each other.
 type Handle struct {
 	drv            driver.Driver
 	migrationsPath string
-	locked         bool
-	fatalErr       error
+	// extraDirs are merged with migrationsPath
+	extraDirs []string
+	// fsys migrations are read from, nil for disk
+	fsys      fs.FS
+	recursive bool
+
+	// mu guards fatalErr, dry run and hook state and released lock holders,
+	// which can be accessed by concurrent read only operations.
+	mu       sync.Mutex
+	fatalErr error
+	// hooking is set while migration hook runs, hookReaders are
+	// read only operations which share the lock with it.
+	hooking     bool
+	hookReaders sync.WaitGroup
+
+	// sem is held together with database lock, so operations
+	// of this Handle don't share it.
+	sem         chan struct{}
+	lockTimeout time.Duration
+	lockBackoff Backoff
+	// abandonedLock is closed once attempt to lock, abandoned
+	// after timeout, completes and releases the lock.
+	abandonedLock <-chan struct{}
 
 	preHook, postHook func(f file.File) error
+	outOfOrder        OutOfOrderPolicy
+	observers         []Observer
+	driftCheck        bool
+	singleTx          bool
+	autoRollback      bool
+	appliedBy         string
+	versionGenerator  VersionGenerator
+	createTemplates   string
+	now               func() time.Time
+
+	// render is set to all migration files.
+	render file.RenderOptions
+
+	// dryRun collects files instead of applying them, if set.
+	// dryRunVersions tracks versions as if collected files were applied.
+	dryRun         *file.Files
+	dryRunVersions file.Versions
 }
 
 // Open migrations Handle
@@ -52,8 +123,12 @@ func New(drv driver.Driver, migrationsPath string, opts ...Option) (*Handle, err
 		return nil, errors.New("driver can't be nil")
 	}
 	h := &Handle{
-		drv:            drv,
-		migrationsPath: migrationsPath,
+		drv:              drv,
+		migrationsPath:   migrationsPath,
+		appliedBy:        currentUser(),
+		versionGenerator: TimestampVersion,
+		now:              time.Now,
+		sem:              make(chan struct{}, 1),
 	}
 	for _, configure := range opts {
 		err := configure(h)
@@ -61,13 +136,26 @@ func New(drv driver.Driver, migrationsPath string, opts ...Option) (*Handle, err
 			return nil, err
 		}
 	}
+	for _, dir := range h.dirs() {
+		if h.fsys != nil && !fs.ValidPath(dir) {
+			return nil, fmt.Errorf("invalid migrations path %q within fs.FS", dir)
+		}
+	}
+	if _, ok := drv.(driver.Transactional); h.singleTx && !ok {
+		return nil, fmt.Errorf("driver %T can't run migrations in single transaction", drv)
+	}
+	driver.SetAppliedBy(drv, h.appliedBy)
+	h.render.Funcs = templateFuncs(drv, h.render.Funcs)
+	if err := h.checkPartials(); err != nil {
+		return nil, err
+	}
 	return h, nil
 }
 
 // Up applies all available migrations.
 func (m *Handle) Up(ctx context.Context) error {
-	return m.locking(ctx, func() error {
-		files, versions, err := m.readFilesAndGetVersions()
+	return m.migrating(ctx, "Up", func(ctx context.Context) error {
+		files, versions, err := m.readFilesAndGetVersions(ctx)
 		if err != nil {
 			return err
 		}
@@ -75,20 +163,20 @@ func (m *Handle) Up(ctx context.Context) error {
 		if err != nil {
 			return err
 		}
-		for _, f := range applyMigrationFiles {
-			err = m.drvMigrate(ctx, f)
-			if err != nil {
-				return err
-			}
+		if err = m.checkDrift(files, applyMigrationFiles); err != nil {
+			return err
 		}
-		return nil
+		if err = m.checkOutOfOrder(applyMigrationFiles, versions); err != nil {
+			return err
+		}
+		return m.migrateFiles(ctx, files, applyMigrationFiles)
 	})
 }
 
 // Down rolls back all migrations.
 func (m *Handle) Down(ctx context.Context) error {
-	return m.locking(ctx, func() error {
-		files, versions, err := m.readFilesAndGetVersions()
+	return m.migrating(ctx, "Down", func(ctx context.Context) error {
+		files, versions, err := m.readFilesAndGetVersions(ctx)
 		if err != nil {
 			return err
 		}
@@ -97,20 +185,29 @@ func (m *Handle) Down(ctx context.Context) error {
 			return err
 		}
 
-		for _, f := range applyMigrationFiles {
-			err = m.drvMigrate(ctx, f)
-			if err != nil {
-				break
-			}
-		}
-		return err
+		return m.migrateFiles(ctx, files, applyMigrationFiles)
 	})
 }
 
 // Redo rolls back the most recently applied migration, then runs it again.
 func (m *Handle) Redo(ctx context.Context) error {
-	return m.locking(ctx, func() error {
-		err := m.Migrate(ctx, -1)
+	return m.migrating(ctx, "Redo", func(ctx context.Context) error {
+		err := m.checkRunPlaceholders(ctx, func(files file.MigrationFiles, versions file.Versions) (file.Files, error) {
+			plan, err := files.Relative(-1, versions)
+			if err != nil || len(plan) == 0 {
+				return plan, err
+			}
+			for _, f := range files {
+				if f.Version == plan[0].Version && f.UpFile != nil {
+					plan = append(plan, *f.UpFile)
+				}
+			}
+			return plan, nil
+		})
+		if err != nil {
+			return err
+		}
+		err = m.Migrate(ctx, -1)
 		if err != nil {
 			return err
 		}
@@ -120,8 +217,19 @@ func (m *Handle) Redo(ctx context.Context) error {
 
 // Reset runs the Down and Up migration function.
 func (m *Handle) Reset(ctx context.Context) error {
-	return m.locking(ctx, func() error {
-		err := m.Down(ctx)
+	return m.migrating(ctx, "Reset", func(ctx context.Context) error {
+		err := m.checkRunPlaceholders(ctx, func(files file.MigrationFiles, versions file.Versions) (file.Files, error) {
+			down, err := files.Applied(versions)
+			if err != nil {
+				return nil, err
+			}
+			up, err := files.Pending(file.Versions{})
+			return append(down, up...), err
+		})
+		if err != nil {
+			return err
+		}
+		err = m.Down(ctx)
 		if err != nil {
 			return err
 		}
@@ -131,8 +239,8 @@ func (m *Handle) Reset(ctx context.Context) error {
 
 // Migrate applies relative +n/-n migrations.
 func (m *Handle) Migrate(ctx context.Context, relativeN int) error {
-	return m.locking(ctx, func() error {
-		files, versions, err := m.readFilesAndGetVersions()
+	return m.migrating(ctx, "Migrate", func(ctx context.Context) error {
+		files, versions, err := m.readFilesAndGetVersions(ctx)
 		if err != nil {
 			return err
 		}
@@ -141,45 +249,78 @@ func (m *Handle) Migrate(ctx context.Context, relativeN int) error {
 		if err != nil {
 			return err
 		}
+		if err = m.checkDrift(files, applyMigrationFiles); err != nil {
+			return err
+		}
+		if err = m.checkOutOfOrder(applyMigrationFiles, versions); err != nil {
+			return err
+		}
 
-		for _, f := range applyMigrationFiles {
-			err = m.drvMigrate(ctx, f)
-			if err != nil {
-				break
-			}
+		return m.migrateFiles(ctx, files, applyMigrationFiles)
+	})
+}
+
+// MigrateTo applies or rolls back migrations until given version is the latest
+// applied one. Version 0 rolls back all migrations.
+// Nothing is executed if target version or any required down migration
+// file can't be found on disk.
+func (m *Handle) MigrateTo(ctx context.Context, version file.Version) error {
+	return m.migrating(ctx, "MigrateTo", func(ctx context.Context) error {
+		files, versions, err := m.readFilesAndGetVersions(ctx)
+		if err != nil {
+			return err
 		}
-		return err
+
+		applyMigrationFiles, err := files.To(version, versions)
+		if err != nil {
+			return err
+		}
+		if err = m.checkDrift(files, applyMigrationFiles); err != nil {
+			return err
+		}
+		if err = m.checkOutOfOrder(applyMigrationFiles, versions); err != nil {
+			return err
+		}
+
+		return m.migrateFiles(ctx, files, applyMigrationFiles)
 	})
 }
 
 // Version returns the current migration version.
 func (m *Handle) Version(ctx context.Context) (version file.Version, err error) {
-	unlock, err := m.lock(ctx)
+	ctx, unlock, err := m.rlock(ctx, "Version")
 	if err != nil {
 		return 0, err
 	}
 	defer unlock()
+	if m.dryRun != nil {
+		versions, err := m.versions(ctx)
+		if err != nil || len(versions) == 0 {
+			return 0, err
+		}
+		return versions[0], nil
+	}
 	return m.drv.Version()
 }
 
 // Versions returns applied versions.
 func (m *Handle) Versions(ctx context.Context) (versions file.Versions, err error) {
-	unlock, err := m.lock(ctx)
+	ctx, unlock, err := m.rlock(ctx, "Versions")
 	if err != nil {
 		return nil, err
 	}
 	defer unlock()
-	return m.drv.Versions()
+	return m.versions(ctx)
 }
 
 // PendingMigrations returns list of pending migration files
 func (m *Handle) PendingMigrations(ctx context.Context) (file.Files, error) {
-	unlock, err := m.lock(ctx)
+	ctx, unlock, err := m.rlock(ctx, "PendingMigrations")
 	if err != nil {
 		return nil, err
 	}
 	defer unlock()
-	files, versions, err := m.readFilesAndGetVersions()
+	files, versions, err := m.readFilesAndGetVersions(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -187,55 +328,80 @@ func (m *Handle) PendingMigrations(ctx context.Context) (file.Files, error) {
 }
 
 // Create creates new migration files on disk.
+// Version is generated by VersionGenerator, TimestampVersion by default,
+// and can't be the version of an existing migration.
+// Files are filled from WithCreateTemplates templates, if set.
+// It fails if migrations are read from fs.FS.
 func (m *Handle) Create(name string) (*file.MigrationFile, error) {
-	files, _, err := m.readFilesAndGetVersions()
+	if m.fsys != nil {
+		return nil, errors.New("can't create migration files: migrations source isn't a directory on disk")
+	}
+	files, err := m.readFiles()
+	if err != nil {
+		return nil, err
+	}
+	version, versionStr, err := m.nextVersion(files)
 	if err != nil {
 		return nil, err
 	}
 
-	versionStr := time.Now().UTC().Format("20060102150405")
-	v, _ := strconv.ParseUint(versionStr, 10, 64)
-	version := file.Version(v)
-
-	filenamef := "%d_%s.%s.%s"
 	name = strings.Replace(name, " ", "_", -1)
-
-	// if latest version has the same timestamp, increment version
-	if len(files) > 0 {
-		latest := files[len(files)-1].Version
-		if latest >= version {
-			version = latest + 1
-		}
+	data := CreateTemplateData{
+		Name:    name,
+		Version: versionStr,
+		Author:  m.appliedBy,
+		Date:    m.now(),
 	}
 
-	mfile := &file.MigrationFile{
-		Version: version,
-		UpFile: &file.File{
-			Path:      m.migrationsPath,
-			FileName:  fmt.Sprintf(filenamef, version, name, "up", driver.FileExtension(m.drv)),
-			Name:      name,
-			Content:   driver.FileTemplate(m.drv),
-			Direction: direction.Up,
-		},
-		DownFile: &file.File{
+	mfile := &file.MigrationFile{Version: version}
+	for _, d := range []direction.Direction{direction.Up, direction.Down} {
+		content, isTemplate, err := m.createContent(d, data)
+		if err != nil {
+			return nil, err
+		}
+		filename := fmt.Sprintf("%s_%s.%s.%s", versionStr, name, d, driver.FileExtension(m.drv))
+		if isTemplate {
+			filename += ".tpl"
+		}
+		f := &file.File{
 			Path:      m.migrationsPath,
-			FileName:  fmt.Sprintf(filenamef, version, name, "down", driver.FileExtension(m.drv)),
+			FileName:  filename,
 			Name:      name,
-			Content:   driver.FileTemplate(m.drv),
-			Direction: direction.Down,
-		},
+			Content:   content,
+			Direction: d,
+		}
+		if d == direction.Up {
+			mfile.UpFile = f
+		} else {
+			mfile.DownFile = f
+		}
 	}
 
-	if err := ioutil.WriteFile(path.Join(mfile.UpFile.Path, mfile.UpFile.FileName), mfile.UpFile.Content, 0644); err != nil {
+	upPath := path.Join(mfile.UpFile.Path, mfile.UpFile.FileName)
+	if err := writeNewFile(upPath, mfile.UpFile.Content); err != nil {
 		return nil, err
 	}
-	if err := ioutil.WriteFile(path.Join(mfile.DownFile.Path, mfile.DownFile.FileName), mfile.DownFile.Content, 0644); err != nil {
+	if err := writeNewFile(path.Join(mfile.DownFile.Path, mfile.DownFile.FileName), mfile.DownFile.Content); err != nil {
+		os.Remove(upPath)
 		return nil, err
 	}
 
 	return mfile, nil
 }
 
+// writeNewFile writes file, failing if it already exists.
+func writeNewFile(name string, content []byte) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 // ApplyVersion applies specific version.
 func (m *Handle) ApplyVersion(ctx context.Context, version file.Version) error {
 	return m.migrateVersion(ctx, version, direction.Up)
@@ -257,8 +423,12 @@ func (m *Handle) migrateVersion(ctx context.Context, version file.Version, d dir
 	if d != direction.Up && d != direction.Down {
 		return fmt.Errorf("invalid direction: %v", d)
 	}
-	return m.locking(ctx, func() error {
-		files, versions, err := m.readFilesAndGetVersions()
+	op := "ApplyVersion"
+	if d == direction.Down {
+		op = "RollbackVersion"
+	}
+	return m.migrating(ctx, op, func(ctx context.Context) error {
+		files, versions, err := m.readFilesAndGetVersions(ctx)
 		if err != nil {
 			return err
 		}
@@ -271,8 +441,23 @@ func (m *Handle) migrateVersion(ctx context.Context, version file.Version, d dir
 		var migration *file.File
 		for _, f := range files {
 			if f.Version == version {
+				if d == direction.Down {
+					if err = f.CheckReversible(); err != nil {
+						return err
+					}
+				}
 				if migration = getFileForDirection(f, d); migration != nil {
-					return m.drvMigrate(ctx, *migration)
+					plan := file.Files{*migration}
+					if err = m.checkDrift(files, plan); err != nil {
+						return err
+					}
+					if err = m.checkOutOfOrder(plan, versions); err != nil {
+						return err
+					}
+					if err = m.checkPlaceholders(files, plan); err != nil {
+						return err
+					}
+					return m.drvMigrate(ctx, plan[0])
 				}
 				break
 			}
@@ -282,72 +467,347 @@ func (m *Handle) migrateVersion(ctx context.Context, version file.Version, d dir
 	})
 }
 
-func (m *Handle) lock(ctx context.Context) (unlock func(), err error) {
-	if m.fatalErr != nil {
-		return nil, m.fatalErr
+// lockKey is the context key of lockHolder.
+type lockKey struct{}
+
+// lockHolder is stored in context of operation holding the lock,
+// so nested calls with that context don't try to lock again.
+type lockHolder struct {
+	m  *Handle
+	op string
+	// tx is set in single transaction mode.
+	tx driver.Tx
+	// released is set once lock or transaction is released,
+	// so context outliving the operation doesn't bypass the lock.
+	released bool
+}
+
+// heldBy returns lock holder carried by ctx, if it still holds lock of this Handle.
+func (m *Handle) heldBy(ctx context.Context) *lockHolder {
+	h, ok := ctx.Value(lockKey{}).(*lockHolder)
+	if !ok || h.m != m {
+		return nil
 	}
-	if m.locked {
-		return func() {}, nil
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if h.released {
+		return nil
 	}
-	select {
-	case err := <-drvLockChan(m.drv):
-		if err != nil {
-			return nil, err
-		}
-	case <-ctx.Done():
-		return nil, ctx.Err()
+	return h
+}
+
+// release marks lock holder as released.
+func (m *Handle) release(h *lockHolder) {
+	m.mu.Lock()
+	h.released = true
+	m.mu.Unlock()
+}
+
+// holder returns operation holding the lock of this Handle, if ctx carries it.
+func (m *Handle) holder(ctx context.Context) (op string, ok bool) {
+	if h := m.heldBy(ctx); h != nil {
+		return h.op, true
+	}
+	return "", false
+}
+
+// tx returns transaction of single transaction mode, if ctx carries it.
+func (m *Handle) tx(ctx context.Context) driver.Tx {
+	if h := m.heldBy(ctx); h != nil {
+		return h.tx
+	}
+	return nil
+}
+
+// lock acquires database lock for operation op, unless ctx shows it's already
+// held by outer operation. Returned context carries the lock for nested calls,
+// returned function releases it.
+func (m *Handle) lock(ctx context.Context, op string) (context.Context, func(), error) {
+	if _, ok := m.holder(ctx); ok {
+		return ctx, func() {}, nil
+	}
+	if err := m.err(); err != nil {
+		return nil, nil, err
+	}
+	start := time.Now()
+	if err := m.acquireLock(ctx, op); err != nil {
+		return nil, nil, err
+	}
+	acquired := time.Now()
+	holder := &lockHolder{m: m, op: op}
+	locked := context.WithValue(ctx, lockKey{}, holder)
+	m.notify(Event{Type: LockAcquired, Op: op, Duration: acquired.Sub(start), Context: locked})
+	return locked, func() {
+		m.release(holder)
+		m.unlock()
+		m.notify(Event{Type: LockReleased, Op: op, Duration: time.Since(acquired), Context: ctx})
+	}, nil
+}
+
+// rlock locks database for read only operation op, if driver requires it.
+// Drivers which aren't driver.Lockable can be queried while migrating,
+// others only while migration hook runs, as nothing else is executed then.
+func (m *Handle) rlock(ctx context.Context, op string) (context.Context, func(), error) {
+	_, lockable := m.drv.(driver.Lockable)
+	inHook := lockable && m.readingInHook()
+	if lockable && !inHook {
+		return m.lock(ctx, op)
+	}
+	unlock := func() {}
+	if inHook {
+		unlock = m.hookReaders.Done
+	}
+	if err := m.err(); err != nil {
+		unlock()
+		return nil, nil, err
+	}
+	return ctx, unlock, nil
+}
+
+// readingInHook registers read only operation sharing the lock
+// with running migration hook, if there's one.
+func (m *Handle) readingInHook() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.hooking {
+		m.hookReaders.Add(1)
+	}
+	return m.hooking
+}
+
+// runHook runs migration hook, letting read only operations
+// called by it share the lock. Operation continues once they're done.
+func (m *Handle) runHook(hook func(f file.File) error, name string, f file.File) error {
+	if hook == nil {
+		return nil
 	}
-	m.locked = true
-	return m.unlock, nil
+	m.mu.Lock()
+	m.hooking = true
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		m.hooking = false
+		m.mu.Unlock()
+		m.hookReaders.Wait()
+	}()
+	return runHookIfNotNil(hook, name, f)
 }
 
 func (m *Handle) unlock() {
+	defer func() { <-m.sem }()
 	err := driver.Unlock(m.drv)
 	if err == nil {
-		m.locked = false
 		return
 	}
 	m.Close()
+	m.mu.Lock()
 	m.fatalErr = fmt.Errorf("connection closed, this handle is no longer usable - failed to unlock database after last session: %s", err)
+	m.mu.Unlock()
+}
+
+// err returns error which made this Handle unusable.
+func (m *Handle) err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.fatalErr
 }
 
-func (m *Handle) locking(ctx context.Context, f func() error) error {
-	unlock, err := m.lock(ctx)
+// locking runs f under database lock, passing it context which carries the lock.
+// Outermost call reports run events of operation op to observers.
+func (m *Handle) locking(ctx context.Context, op string, f func(ctx context.Context) error) (err error) {
+	_, nested := m.holder(ctx)
+	start := time.Now()
+	if !nested {
+		m.notify(Event{Type: RunStart, Op: op, Context: ctx})
+	}
+	runCtx := ctx
+	finish := func() {
+		if !nested {
+			m.notify(Event{Type: RunFinish, Op: op, Duration: time.Since(start), Err: err, Context: runCtx})
+		}
+	}
+	locked, unlock, err := m.lock(ctx, op)
 	if err != nil {
+		finish()
 		return err
 	}
+	runCtx = locked
 	defer unlock()
-	return f()
+	defer finish()
+	if !nested && m.dryRun != nil {
+		// simulation starts over from database state
+		m.mu.Lock()
+		m.dryRunVersions = nil
+		m.mu.Unlock()
+	}
+	return f(locked)
 }
 
+// migrating runs f under database lock, like locking. In single transaction
+// mode outermost call runs f in transaction, which is committed if f succeeds.
+func (m *Handle) migrating(ctx context.Context, op string, f func(ctx context.Context) error) error {
+	if !m.singleTx || m.dryRun != nil {
+		return m.locking(ctx, op, f)
+	}
+	return m.locking(ctx, op, func(ctx context.Context) error {
+		if m.tx(ctx) != nil {
+			return f(ctx)
+		}
+		tx, err := m.drv.(driver.Transactional).BeginTx(ctx)
+		if err != nil {
+			return err
+		}
+		holder := &lockHolder{m: m, op: op, tx: tx}
+		err = f(context.WithValue(ctx, lockKey{}, holder))
+		m.release(holder)
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				return fmt.Errorf("%s; failed to roll back transaction: %s", err, rbErr)
+			}
+			return err
+		}
+		return tx.Commit()
+	})
+}
+
+// drvMigrate applies migration file, unless database is dirty.
 func (m *Handle) drvMigrate(ctx context.Context, f file.File) error {
+	if err := m.checkDirty(); err != nil {
+		return err
+	}
+	return m.apply(ctx, f)
+}
+
+// apply applies migration file, running hooks and notifying observers.
+func (m *Handle) apply(ctx context.Context, f file.File) error {
 	select {
 	case <-ctx.Done():
 		return fmt.Errorf("interrupted before applying version %d: %s", f.Version, ctx.Err())
 	default:
-		err := runHookIfNotNil(m.preHook, "pre", f)
+		if m.dryRun != nil {
+			return m.dryRunMigrate(f)
+		}
+		err := m.runHook(m.preHook, "pre", f)
 		if err != nil {
 			return err
 		}
-		err = m.drv.Migrate(f)
+		op, _ := m.holder(ctx)
+		event := Event{Op: op, Direction: f.Direction, Version: f.Version, FileName: f.FileName, Source: f.Source, Context: ctx}
+		m.notify(event.with(MigrationStart, 0, nil))
+		start := time.Now()
+		if tx := m.tx(ctx); tx != nil {
+			err = tx.MigrateContext(ctx, f)
+		} else {
+			err = driver.MigrateContext(ctx, m.drv, f)
+		}
 		if err != nil {
+			if len(m.extraDirs) > 0 {
+				err = fmt.Errorf("%s: %w", path.Join(f.Source, f.FileName), err)
+			}
+			m.notify(event.with(MigrationFailure, time.Since(start), err))
 			return err
 		}
-		return runHookIfNotNil(m.postHook, "post", f)
+		m.notify(event.with(MigrationSuccess, time.Since(start), nil))
+		return m.runHook(m.postHook, "post", f)
 	}
 }
 
 // readFilesAndGetVersions is a small helper
 // function that is common to most of the migration funcs.
-func (m *Handle) readFilesAndGetVersions() (file.MigrationFiles, file.Versions, error) {
-	files, err := file.ReadMigrationFiles(m.migrationsPath, file.FilenameRegex(driver.FileExtension(m.drv)))
+func (m *Handle) readFilesAndGetVersions(ctx context.Context) (file.MigrationFiles, file.Versions, error) {
+	files, err := m.readFiles()
 	if err != nil {
 		return nil, file.Versions{}, err
 	}
-	versions, err := m.drv.Versions()
+	versions, err := m.versions(ctx)
 	return files, versions, err
 }
 
+// readFiles reads migration files from migrations path
+// and extra directories.
+func (m *Handle) readFiles() (file.MigrationFiles, error) {
+	sets := make([]file.MigrationFiles, 0, len(m.extraDirs)+1)
+	for _, dir := range m.dirs() {
+		files, err := m.readDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, files)
+	}
+	return file.MergeMigrationFiles(sets...)
+}
+
+// readDir reads migration files from single migrations directory.
+func (m *Handle) readDir(dir string) (file.MigrationFiles, error) {
+	return file.ReadMigrationFilesWith(dir, file.FilenameRegex(driver.FileExtension(m.drv)), m.fileOptions())
+}
+
+// fileOptions returns options reading and rendering migration files as set on m.
+func (m *Handle) fileOptions() file.Options {
+	return file.Options{FS: m.fsys, Recursive: m.recursive, Render: &m.render}
+}
+
+// dirs returns all migrations directories.
+func (m *Handle) dirs() []string {
+	return append([]string{m.migrationsPath}, m.extraDirs...)
+}
+
+// versions returns applied versions, newest first.
+// In dry run mode it accounts for migrations collected so far.
+func (m *Handle) versions(ctx context.Context) (file.Versions, error) {
+	if tx := m.tx(ctx); tx != nil {
+		return tx.VersionsContext(ctx)
+	}
+	if m.dryRun == nil {
+		return driver.VersionsContext(ctx, m.drv)
+	}
+	m.mu.Lock()
+	simulated := m.dryRunVersions != nil
+	m.mu.Unlock()
+	if !simulated {
+		versions, err := driver.VersionsContext(ctx, m.drv)
+		if err != nil {
+			return nil, err
+		}
+		m.mu.Lock()
+		if m.dryRunVersions == nil {
+			m.dryRunVersions = append(file.Versions{}, versions...)
+			sort.Sort(sort.Reverse(m.dryRunVersions))
+		}
+		m.mu.Unlock()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append(file.Versions{}, m.dryRunVersions...), nil
+}
+
+// dryRunMigrate renders given file and adds it to the plan.
+func (m *Handle) dryRunMigrate(f file.File) error {
+	if err := f.ReadContent(); err != nil {
+		return err
+	}
+	*m.dryRun = append(*m.dryRun, f)
+	m.dryRunApply(f.Version, f.Direction == direction.Up)
+	return nil
+}
+
+// dryRunApply updates versions simulated in dry run mode.
+func (m *Handle) dryRunApply(version file.Version, applied bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if applied {
+		m.dryRunVersions = append(m.dryRunVersions, version)
+		sort.Sort(sort.Reverse(m.dryRunVersions))
+		return
+	}
+	for i, v := range m.dryRunVersions {
+		if v == version {
+			m.dryRunVersions = append(m.dryRunVersions[:i], m.dryRunVersions[i+1:]...)
+			break
+		}
+	}
+}
+
 func runHookIfNotNil(hook func(f file.File) error, name string, f file.File) error {
 	if hook == nil {
 		return nil
@@ -365,14 +825,3 @@ func getFileForDirection(m file.MigrationFile, d direction.Direction) *file.File
 	}
 	return m.DownFile
 }
-
-func drvLockChan(drv driver.Driver) <-chan error {
-	ret := make(chan error)
-	go func() {
-		if err := driver.Lock(drv); err != nil {
-			ret <- err
-		}
-		close(ret)
-	}()
-	return ret
-}
@@ -8,6 +8,7 @@ import (
 	"path"
 	"reflect"
 	"testing"
+	"time"
 
 	// Ensure imports for each driver we wish to test
 
@@ -38,7 +39,13 @@ func TestCreate(t *testing.T) {
 		}
 		defer os.Remove(tmpdir)
 
-		m, err := Open(driverUrl, tmpdir)
+		// timestamps of created migrations must differ
+		now := time.Now()
+		clock := func() time.Time {
+			now = now.Add(time.Second)
+			return now
+		}
+		m, err := Open(driverUrl, tmpdir, WithClock(clock))
 		if err != nil {
 			t.Fatalf("Failed to initialize Handle: %s", err)
 		}
@@ -346,6 +353,160 @@ func TestMigrate(t *testing.T) {
 	}
 }
 
+func TestMigrateTo(t *testing.T) {
+	for _, driverUrl := range driverUrls {
+		t.Logf("Test driver: %s", driverUrl)
+		tmpdir, err := ioutil.TempDir("/tmp", "migrate-test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(tmpdir)
+
+		ctx := context.Background()
+		m, err := Open(driverUrl, tmpdir)
+		if err != nil {
+			t.Fatalf("Failed to initialize Handle: %s", err)
+		}
+
+		file1, _ := m.Create("migration1")
+		file2, _ := m.Create("migration2")
+		file3, _ := m.Create("migration3")
+
+		err = m.MigrateTo(ctx, file2.Version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		version, err := m.Version(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != file2.Version {
+			t.Fatalf("Expected version %d, got %v", file2.Version, version)
+		}
+
+		err = m.MigrateTo(ctx, file3.Version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		version, err = m.Version(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != file3.Version {
+			t.Fatalf("Expected version %d, got %v", file3.Version, version)
+		}
+
+		err = m.MigrateTo(ctx, file1.Version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		version, err = m.Version(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != file1.Version {
+			t.Fatalf("Expected version %d, got %v", file1.Version, version)
+		}
+
+		if err = m.MigrateTo(ctx, file3.Version+1); err == nil {
+			t.Fatal("Expected error for unknown target version")
+		}
+		version, err = m.Version(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != file1.Version {
+			t.Fatalf("Expected version %d, got %v", file1.Version, version)
+		}
+
+		ensureClean(ctx, t, m)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	ctx := context.Background()
+	os.Setenv("MIGRATE_TEST_TABLE", "yolo")
+	var plan file.Files
+	m, drv, cleanup := newMockHandle(t, map[string]string{
+		"001_first.up.sql":        "CREATE TABLE first;",
+		"001_first.down.sql":      "DROP TABLE first;",
+		"002_second.up.sql.tpl":   "CREATE TABLE {{.MIGRATE_TEST_TABLE}};",
+		"002_second.down.sql.tpl": "DROP TABLE {{.MIGRATE_TEST_TABLE}};",
+	}, WithDryRun(&plan))
+	defer cleanup()
+
+	drv.versions = file.Versions{1}
+
+	if err := m.Redo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	version, err := m.Version(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 2 {
+		t.Errorf("Expected dry run version 2, got %d", version)
+	}
+	if err := m.Reset(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// every operation plans from database state
+	drv.versions = file.Versions{}
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Force(ctx, 1); err == nil {
+		t.Error("Expected Force to be refused in dry run")
+	}
+
+	if len(drv.migrated) != 0 {
+		t.Fatalf("Expected no migrations to be applied in dry run, got %v", drv.migrated)
+	}
+	if len(drv.versions) != 0 {
+		t.Fatalf("Expected applied versions to stay untouched, got %v", drv.versions)
+	}
+
+	expected := []string{
+		"DROP TABLE first;",
+		"CREATE TABLE first;",
+		"CREATE TABLE yolo;",
+		"DROP TABLE first;",
+		"CREATE TABLE first;",
+		"CREATE TABLE yolo;",
+		"CREATE TABLE first;",
+		"CREATE TABLE yolo;",
+	}
+	if len(plan) != len(expected) {
+		t.Fatalf("Expected %d planned files, got %d: %v", len(expected), len(plan), plan)
+	}
+	for i, content := range expected {
+		if string(plan[i].Content) != content {
+			t.Errorf("Planned file %d (%s): expected content %q, got %q", i, plan[i].FileName, content, plan[i].Content)
+		}
+	}
+}
+
+func TestCancelRunningMigration(t *testing.T) {
+	m, drv, cleanup := newMockHandle(t, map[string]string{
+		"001_first.up.sql":  "CREATE TABLE first;",
+		"002_second.up.sql": "BLOCK",
+		"003_third.up.sql":  "CREATE TABLE third;",
+	})
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := m.Up(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Expected running migration to be interrupted by deadline, got %v", err)
+	}
+	if !reflect.DeepEqual(drv.versions, file.Versions{1}) {
+		t.Errorf("Expected only version 1 to be applied, got %v", drv.versions)
+	}
+}
+
 func ensureClean(ctx context.Context, t *testing.T, m *Handle) {
 	if err := m.Down(ctx); err != nil {
 		t.Fatal(err)
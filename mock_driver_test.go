@@ -0,0 +1,186 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/db-journey/migrate/v2/direction"
+	"github.com/db-journey/migrate/v2/driver"
+	"github.com/db-journey/migrate/v2/file"
+)
+
+func init() {
+	driver.Register("mock", "sql", nil, func(url string) (driver.Driver, error) {
+		return &mockDriver{}, nil
+	})
+}
+
+// mockDriver keeps applied versions in memory.
+// Migrations containing "FAIL" fail, ones containing "HALFWAY"
+// fail and leave their version dirty, ones containing "BLOCK"
+// run until context is done. It's safe for concurrent use.
+type mockDriver struct {
+	mu        sync.Mutex
+	versions  file.Versions
+	records   map[file.Version]driver.Record
+	appliedBy string
+	migrated  file.Files
+	dirty     file.Versions
+}
+
+func (d *mockDriver) Close() error {
+	return nil
+}
+
+func (d *mockDriver) Migrate(f file.File) error {
+	return d.MigrateContext(context.Background(), f)
+}
+
+func (d *mockDriver) MigrateContext(ctx context.Context, f file.File) error {
+	if err := f.ReadContent(); err != nil {
+		return err
+	}
+	if strings.Contains(string(f.Content), "BLOCK") {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if strings.Contains(string(f.Content), "FAIL") {
+		return errors.New("migration failed")
+	}
+	if strings.Contains(string(f.Content), "HALFWAY") {
+		if f.Direction == direction.Up {
+			d.versions = append(d.versions, f.Version)
+			sort.Sort(sort.Reverse(d.versions))
+		}
+		d.dirty = append(d.dirty, f.Version)
+		return errors.New("migration failed halfway")
+	}
+	d.migrated = append(d.migrated, f)
+	if f.Direction == direction.Up {
+		d.versions = append(d.versions, f.Version)
+		if d.records == nil {
+			d.records = map[file.Version]driver.Record{}
+		}
+		d.records[f.Version] = driver.Record{
+			Version:   f.Version,
+			Name:      f.Name,
+			Checksum:  f.Checksum(),
+			AppliedAt: time.Now(),
+			AppliedBy: d.appliedBy,
+		}
+	} else {
+		for i, v := range d.versions {
+			if v == f.Version {
+				d.versions = append(d.versions[:i], d.versions[i+1:]...)
+				break
+			}
+		}
+	}
+	sort.Sort(sort.Reverse(d.versions))
+	return nil
+}
+
+func (d *mockDriver) Version() (file.Version, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.versions) == 0 {
+		return 0, nil
+	}
+	return d.versions[0], nil
+}
+
+func (d *mockDriver) Versions() (file.Versions, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	versions := make(file.Versions, len(d.versions))
+	copy(versions, d.versions)
+	return versions, nil
+}
+
+func (d *mockDriver) VersionsContext(ctx context.Context) (file.Versions, error) {
+	return d.Versions()
+}
+
+func (d *mockDriver) ExecuteContext(ctx context.Context, statement string) error {
+	return d.Execute(statement)
+}
+
+func (d *mockDriver) Records() ([]driver.Record, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	records := make([]driver.Record, len(d.versions))
+	for i, v := range d.versions {
+		records[i] = d.records[v]
+		records[i].Version = v
+	}
+	return records, nil
+}
+
+func (d *mockDriver) MarkApplied(f file.File) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.versions = append(d.versions, f.Version)
+	sort.Sort(sort.Reverse(d.versions))
+	return nil
+}
+
+func (d *mockDriver) Dirty() (file.Versions, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append(file.Versions{}, d.dirty...), nil
+}
+
+func (d *mockDriver) Force(version file.Version) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, v := range d.dirty {
+		if v == version {
+			d.dirty = append(d.dirty[:i], d.dirty[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (d *mockDriver) SetAppliedBy(appliedBy string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.appliedBy = appliedBy
+}
+
+func (d *mockDriver) Execute(statement string) error {
+	return nil
+}
+
+// newMockHandle writes given migration files into temporary directory
+// and returns Handle with mockDriver for it.
+func newMockHandle(t *testing.T, files map[string]string, opts ...Option) (*Handle, *mockDriver, func()) {
+	tmpdir, err := ioutil.TempDir("/tmp", "migrate-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup := func() { os.RemoveAll(tmpdir) }
+	for name, content := range files {
+		if err := ioutil.WriteFile(path.Join(tmpdir, name), []byte(content), 0644); err != nil {
+			cleanup()
+			t.Fatal(err)
+		}
+	}
+	drv := &mockDriver{}
+	m, err := New(drv, tmpdir, opts...)
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	return m, drv, cleanup
+}
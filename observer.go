@@ -0,0 +1,117 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/db-journey/migrate/v2/direction"
+	"github.com/db-journey/migrate/v2/file"
+)
+
+// EventType identifies what happened.
+type EventType int
+
+// Event types, in order they're reported during a run.
+const (
+	// RunStart is reported when operation that changes database starts.
+	RunStart EventType = iota
+	// LockAcquired is reported once database lock is held.
+	// Duration is the time spent waiting for it.
+	LockAcquired
+	// MigrationStart is reported before migration file is applied.
+	MigrationStart
+	// MigrationSuccess is reported after migration file is applied.
+	// Duration is the time it took.
+	MigrationSuccess
+	// MigrationFailure is reported when migration file fails with Err.
+	// Duration is the time it took.
+	MigrationFailure
+	// RunFinish is reported when operation ends, Err is its result.
+	// Duration is the time whole run took.
+	RunFinish
+	// LockReleased is reported after database lock is released.
+	// Duration is the time it was held.
+	LockReleased
+)
+
+func (t EventType) String() string {
+	switch t {
+	case RunStart:
+		return "run-start"
+	case LockAcquired:
+		return "lock-acquired"
+	case MigrationStart:
+		return "migration-start"
+	case MigrationSuccess:
+		return "migration-success"
+	case MigrationFailure:
+		return "migration-failure"
+	case RunFinish:
+		return "run-finish"
+	case LockReleased:
+		return "lock-released"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes a step of Handle operation.
+// Direction, Version, FileName and Source are only set for migration events.
+type Event struct {
+	Type EventType
+	// Op is the name of Handle method, e.g. "Up" or "MigrateTo".
+	Op        string
+	Direction direction.Direction
+	Version   file.Version
+	FileName  string
+	// Source is the migrations directory of the file.
+	Source   string
+	Duration time.Duration
+	Err      error
+	// Context is the context of operation. While database lock is held,
+	// it carries the lock, so it must be passed to Handle methods called
+	// by observer, which would otherwise wait for the lock forever.
+	// Once the lock is released, methods called with it lock again.
+	Context context.Context
+}
+
+func (e Event) with(t EventType, d time.Duration, err error) Event {
+	e.Type = t
+	e.Duration = d
+	e.Err = err
+	return e
+}
+
+// Observer receives events of Handle operations.
+// Events are delivered synchronously, so Observe should return quickly.
+// Handle methods called by Observe must get Event.Context.
+type Observer interface {
+	Observe(e Event)
+}
+
+// ObserverFunc is an adapter to use ordinary function as Observer.
+type ObserverFunc func(e Event)
+
+// Observe calls f(e).
+func (f ObserverFunc) Observe(e Event) {
+	f(e)
+}
+
+// WithObserver adds observer notified about runs, locking and migrations.
+// Migrations collected in dry run mode are not reported.
+func WithObserver(o Observer) Option {
+	return func(h *Handle) error {
+		if o == nil {
+			return errors.New("observer can't be nil")
+		}
+		h.observers = append(h.observers, o)
+		return nil
+	}
+}
+
+func (m *Handle) notify(e Event) {
+	for _, o := range m.observers {
+		o.Observe(e)
+	}
+}
@@ -0,0 +1,135 @@
+package migrate
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/db-journey/migrate/v2/direction"
+	"github.com/db-journey/migrate/v2/file"
+)
+
+func TestObserver(t *testing.T) {
+	ctx := context.Background()
+	var events []Event
+	m, _, cleanup := newMockHandle(t, map[string]string{
+		"001_first.up.sql":    "CREATE TABLE first;",
+		"001_first.down.sql":  "DROP TABLE first;",
+		"002_second.up.sql":   "FAIL",
+		"002_second.down.sql": "DROP TABLE second;",
+	}, WithObserver(ObserverFunc(func(e Event) {
+		events = append(events, e)
+	})))
+	defer cleanup()
+
+	if err := m.Up(ctx); err == nil {
+		t.Fatal("Expected migration to fail")
+	}
+	var types []EventType
+	for _, e := range events {
+		types = append(types, e.Type)
+		if e.Op != "Up" {
+			t.Errorf("Expected %s event of Up operation, got %q", e.Type, e.Op)
+		}
+	}
+	expected := []EventType{RunStart, LockAcquired,
+		MigrationStart, MigrationSuccess, MigrationStart, MigrationFailure,
+		RunFinish, LockReleased}
+	if !reflect.DeepEqual(types, expected) {
+		t.Fatalf("Expected events %v, got %v", expected, types)
+	}
+	if e := events[5]; e.Version != 2 || e.FileName != "002_second.up.sql" || e.Direction != direction.Up || e.Err == nil {
+		t.Errorf("Unexpected migration failure event: %+v", e)
+	}
+	if events[6].Err == nil {
+		t.Error("Expected run finish event to carry the error")
+	}
+
+	// nested operations are reported as single run
+	events = nil
+	if err := m.Redo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	runs := 0
+	for _, e := range events {
+		if e.Type == RunStart {
+			runs++
+		}
+		if e.Op != "Redo" {
+			t.Errorf("Expected %s event of Redo operation, got %q", e.Type, e.Op)
+		}
+	}
+	if runs != 1 {
+		t.Errorf("Expected single run, got %d", runs)
+	}
+}
+
+func TestObserverNestedCall(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("/tmp", "migrate-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	for _, name := range []string{"001_first.up.sql", "002_second.up.sql"} {
+		if err := ioutil.WriteFile(path.Join(tmpdir, name), []byte("CREATE TABLE t;"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var m *Handle
+	var versions []file.Version
+	var finished context.Context
+	observer := ObserverFunc(func(e Event) {
+		if e.Type == RunFinish {
+			finished = e.Context
+		}
+		if e.Type != MigrationStart {
+			return
+		}
+		// lockable driver is locked during migration,
+		// Handle methods can only be called with context carrying the lock
+		version, err := m.Version(e.Context)
+		if err != nil {
+			t.Error(err)
+		}
+		versions = append(versions, version)
+	})
+	drv := &lockableMockDriver{}
+	m, err = New(drv, tmpdir, WithObserver(observer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- m.Up(context.Background())
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Observer calling Handle method blocked on the lock")
+	}
+	if expected := []file.Version{0, 1}; !reflect.DeepEqual(versions, expected) {
+		t.Errorf("Expected versions %v, got %v", expected, versions)
+	}
+
+	// context kept after operation doesn't carry released lock
+	drv.lockMu.Lock()
+	attempts := drv.attempts
+	drv.lockMu.Unlock()
+	if _, err := m.Version(finished); err != nil {
+		t.Fatal(err)
+	}
+	drv.lockMu.Lock()
+	defer drv.lockMu.Unlock()
+	if drv.attempts != attempts+1 {
+		t.Errorf("Expected Version to lock again with context of finished run")
+	}
+}
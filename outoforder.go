@@ -0,0 +1,83 @@
+package migrate
+
+import (
+	"fmt"
+
+	"github.com/db-journey/migrate/v2/direction"
+	"github.com/db-journey/migrate/v2/file"
+)
+
+// OutOfOrderPolicy decides what to do with pending migrations older than
+// the latest applied version (e.g. ones that came with a late-merged branch).
+// It receives versions of such migrations; returned error aborts
+// the operation before anything is applied.
+type OutOfOrderPolicy func(versions file.Versions) error
+
+// AllowOutOfOrder applies out of order migrations silently.
+// This is the default policy.
+func AllowOutOfOrder(versions file.Versions) error {
+	return nil
+}
+
+// RefuseOutOfOrder refuses to apply out of order migrations.
+func RefuseOutOfOrder(versions file.Versions) error {
+	return fmt.Errorf("refusing to apply migrations older than the latest applied version: %v", versions)
+}
+
+// WarnOutOfOrder returns policy that applies out of order migrations,
+// but calls warn with their versions first.
+func WarnOutOfOrder(warn func(versions file.Versions)) OutOfOrderPolicy {
+	return func(versions file.Versions) error {
+		warn(versions)
+		return nil
+	}
+}
+
+// WithOutOfOrderPolicy sets policy for out of order migrations,
+// see OutOfOrderPolicy. It's applied by Up, Migrate, MigrateTo and ApplyVersion.
+func WithOutOfOrderPolicy(policy OutOfOrderPolicy) Option {
+	return func(h *Handle) error {
+		h.outOfOrder = policy
+		return nil
+	}
+}
+
+// checkOutOfOrder applies out of order policy to given migration plan.
+func (m *Handle) checkOutOfOrder(plan file.Files, versions file.Versions) error {
+	if m.outOfOrder == nil {
+		return nil
+	}
+	if outOfOrder := outOfOrderVersions(plan, versions); len(outOfOrder) > 0 {
+		return m.outOfOrder(outOfOrder)
+	}
+	return nil
+}
+
+// outOfOrderVersions returns versions of up files in plan that are older
+// than the latest applied version at the moment they would be applied.
+func outOfOrderVersions(plan file.Files, versions file.Versions) file.Versions {
+	applied := make(map[file.Version]bool, len(versions))
+	for _, v := range versions {
+		applied[v] = true
+	}
+	latest := func() (latest file.Version) {
+		for v := range applied {
+			if v > latest {
+				latest = v
+			}
+		}
+		return latest
+	}
+	var outOfOrder file.Versions
+	for _, f := range plan {
+		if f.Direction == direction.Down {
+			delete(applied, f.Version)
+			continue
+		}
+		if f.Version < latest() {
+			outOfOrder = append(outOfOrder, f.Version)
+		}
+		applied[f.Version] = true
+	}
+	return outOfOrder
+}
@@ -0,0 +1,65 @@
+package migrate
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/db-journey/migrate/v2/file"
+)
+
+var outOfOrderMigrations = map[string]string{
+	"001_first.up.sql":    "",
+	"001_first.down.sql":  "",
+	"002_second.up.sql":   "",
+	"002_second.down.sql": "",
+	"003_third.up.sql":    "",
+	"003_third.down.sql":  "",
+}
+
+func TestRefuseOutOfOrder(t *testing.T) {
+	ctx := context.Background()
+	m, drv, cleanup := newMockHandle(t, outOfOrderMigrations, WithOutOfOrderPolicy(RefuseOutOfOrder))
+	defer cleanup()
+	drv.versions = file.Versions{3, 1}
+
+	if err := m.Up(ctx); err == nil {
+		t.Error("Expected Up to refuse out of order migration")
+	}
+	if err := m.Migrate(ctx, +1); err == nil {
+		t.Error("Expected Migrate to refuse out of order migration")
+	}
+	if err := m.ApplyVersion(ctx, 2); err == nil {
+		t.Error("Expected ApplyVersion to refuse out of order migration")
+	}
+	if len(drv.migrated) != 0 {
+		t.Fatalf("Expected no migrations to be applied, got %v", drv.migrated)
+	}
+
+	// rolling back 3 first makes 2 the next migration in order
+	if err := m.MigrateTo(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(drv.versions, file.Versions{2, 1}) {
+		t.Errorf("Expected versions %v, got %v", file.Versions{2, 1}, drv.versions)
+	}
+}
+
+func TestWarnOutOfOrder(t *testing.T) {
+	var warned file.Versions
+	m, drv, cleanup := newMockHandle(t, outOfOrderMigrations, WithOutOfOrderPolicy(WarnOutOfOrder(func(versions file.Versions) {
+		warned = append(warned, versions...)
+	})))
+	defer cleanup()
+	drv.versions = file.Versions{3}
+
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(warned, file.Versions{1, 2}) {
+		t.Errorf("Expected warning for versions %v, got %v", file.Versions{1, 2}, warned)
+	}
+	if !reflect.DeepEqual(drv.versions, file.Versions{3, 2, 1}) {
+		t.Errorf("Expected versions %v, got %v", file.Versions{3, 2, 1}, drv.versions)
+	}
+}
@@ -0,0 +1,59 @@
+package migrate
+
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/db-journey/migrate/v2/file"
+)
+
+// WithFS makes Handle read migrations from fsys instead of disk,
+// e.g. from files embedded with go:embed.
+// Migrations path given to New or Open is then a slash separated
+// path within fsys, "." for its root. Create refuses to write to fsys.
+func WithFS(fsys fs.FS) Option {
+	return func(h *Handle) error {
+		if fsys == nil {
+			return errors.New("fs.FS can't be nil")
+		}
+		h.fsys = fsys
+		return nil
+	}
+}
+
+// WithExtraDirs merges migrations from given directories with ones
+// in migrations path, e.g. when each module of a service owns its migrations.
+// Directories are read from the same file system as migrations path.
+// A version can't be defined in more than one directory.
+// File.Source tells which directory a migration file comes from,
+// Create writes new files to migrations path.
+func WithExtraDirs(dirs ...string) Option {
+	return func(h *Handle) error {
+		h.extraDirs = append(h.extraDirs, dirs...)
+		return nil
+	}
+}
+
+// WithRecursive makes Handle read migrations from subdirectories
+// of migrations directories too, e.g. migrations/2023/..., migrations/2024/...
+// Hidden subdirectories are skipped. Create writes new files
+// to the top level of migrations path.
+func WithRecursive() Option {
+	return func(h *Handle) error {
+		h.recursive = true
+		return nil
+	}
+}
+
+// WithArchive makes Handle read migrations from .tar, .tar.gz, .tgz
+// or .zip archive, see WithFS. Migrations path is relative to the archive root.
+func WithArchive(name string) Option {
+	return func(h *Handle) error {
+		fsys, err := file.OpenArchive(name)
+		if err != nil {
+			return err
+		}
+		h.fsys = fsys
+		return nil
+	}
+}
@@ -0,0 +1,193 @@
+package migrate
+
+import (
+	"archive/zip"
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/db-journey/migrate/v2/file"
+)
+
+func TestWithFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/001_first.up.sql":      {Data: []byte("CREATE TABLE first;")},
+		"migrations/001_first.down.sql":    {Data: []byte("DROP TABLE first;")},
+		"migrations/002_second.up.sql.tpl": {Data: []byte(`CREATE TABLE {{ "second" }};`)},
+		"migrations/002_second.down.sql":   {Data: []byte("DROP TABLE second;")},
+	}
+	drv := &mockDriver{}
+	m, err := New(drv, "migrations", WithFS(fsys))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(drv.migrated) != 2 {
+		t.Fatalf("expected 2 migrations, got %v", drv.migrated)
+	}
+	if content := string(drv.migrated[1].Content); content != "CREATE TABLE second;" {
+		t.Errorf("expected rendered template, got %q", content)
+	}
+	if drv.migrated[1].FS == nil {
+		t.Error("expected file to refer to fs.FS")
+	}
+
+	if diags, err := m.Validate(); err != nil || len(diags) != 0 {
+		t.Errorf("expected no diagnostics, got %v, %v", diags, err)
+	}
+	if _, err := m.Create("third"); err == nil {
+		t.Error("expected Create to fail for fs.FS source")
+	}
+
+	if _, err := New(drv, "/migrations", WithFS(fsys)); err == nil {
+		t.Error("expected error for invalid path within fs.FS")
+	}
+}
+
+func TestWithFSVersions(t *testing.T) {
+	fsys := fstest.MapFS{
+		"001_first.up.sql": {Data: []byte("CREATE TABLE first;")},
+	}
+	m, err := New(&mockDriver{}, ".", WithFS(fsys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := m.PendingMigrations(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Version != file.Version(1) {
+		t.Fatalf("expected pending version 1, got %v", files)
+	}
+}
+
+func TestWithArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp", "migrate-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	archive := path.Join(dir, "migrations.zip")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, content := range map[string]string{
+		"migrations/001_first.up.sql":   "CREATE TABLE first;",
+		"migrations/001_first.down.sql": "DROP TABLE first;",
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	drv := &mockDriver{}
+	m, err := New(drv, "migrations", WithArchive(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(drv.migrated) != 1 || drv.migrated[0].Path != "migrations" {
+		t.Fatalf("expected migration from archive, got %v", drv.migrated)
+	}
+
+	if _, err := New(drv, "migrations", WithArchive(path.Join(dir, "missing.zip"))); err == nil {
+		t.Error("expected error for missing archive")
+	}
+}
+
+func TestWithExtraDirs(t *testing.T) {
+	fsys := fstest.MapFS{
+		"users/001_users.up.sql":      {Data: []byte("CREATE TABLE users;")},
+		"billing/002_invoices.up.sql": {Data: []byte("CREATE TABLE invoices;")},
+		"billing/003_payments.up.sql": {Data: []byte("FAIL")},
+		"orders/002_orders.up.sql":    {Data: []byte("CREATE TABLE orders;")},
+	}
+	var sources []string
+	drv := &mockDriver{}
+	m, err := New(drv, "users", WithFS(fsys), WithExtraDirs("billing"),
+		WithObserver(ObserverFunc(func(e Event) {
+			if e.Type == MigrationStart {
+				sources = append(sources, e.Source)
+			}
+		})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.Up(context.Background())
+	if err == nil || !strings.HasPrefix(err.Error(), "billing/003_payments.up.sql: ") {
+		t.Fatalf("expected error naming the failed file, got %v", err)
+	}
+	if !reflect.DeepEqual(sources, []string{"users", "billing", "billing"}) {
+		t.Errorf("unexpected sources of applied migrations %v", sources)
+	}
+
+	m, err = New(drv, "users", WithFS(fsys), WithExtraDirs("billing", "orders"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.PendingMigrations(context.Background()); err == nil {
+		t.Error("expected error for version defined in two directories")
+	}
+	diags, err := m.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diags) != 1 || diags[0].Kind != file.DuplicateVersion || len(diags[0].FileNames) != 2 {
+		t.Errorf("expected duplicate version diagnostic, got %v", diags)
+	}
+}
+
+func TestWithRecursive(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/2023/001_first.up.sql":        {Data: []byte("CREATE TABLE first;")},
+		"migrations/2023/001_first.down.sql":      {Data: []byte("DROP TABLE first;")},
+		"migrations/2024/002_second.up.sql":       {Data: []byte("CREATE TABLE second;")},
+		"migrations/2024/old/002_second.down.sql": {Data: []byte("DROP TABLE second;")},
+	}
+	drv := &mockDriver{}
+	m, err := New(drv, "migrations", WithFS(fsys), WithRecursive())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Migrate(context.Background(), -1); err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, f := range drv.migrated {
+		paths = append(paths, path.Join(f.Path, f.FileName))
+	}
+	expected := []string{
+		"migrations/2023/001_first.up.sql",
+		"migrations/2024/002_second.up.sql",
+		"migrations/2024/old/002_second.down.sql",
+	}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected %v, got %v", expected, paths)
+	}
+	if diags, err := m.Validate(); err != nil || len(diags) != 0 {
+		t.Errorf("expected no diagnostics, got %v, %v", diags, err)
+	}
+}
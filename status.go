@@ -0,0 +1,90 @@
+package migrate
+
+import (
+	"context"
+	"sort"
+
+	"github.com/db-journey/migrate/v2/file"
+)
+
+// MigrationStatus describes the state of a single migration version.
+type MigrationStatus struct {
+	Version file.Version
+
+	// Name of the migration, empty if there are no files for it on disk.
+	Name string
+
+	// Applied is set if version is recorded in the version table.
+	Applied bool
+
+	// Pending is set if version is not applied and has an up file.
+	Pending bool
+
+	// Orphaned is set if version is applied, but has no files on disk.
+	Orphaned bool
+
+	// OutOfOrder is set if version is pending, but older than
+	// the latest applied version.
+	OutOfOrder bool
+
+	HasUpFile   bool
+	HasDownFile bool
+}
+
+// Status returns status of every version found on disk or in the version table,
+// ordered by version.
+func (m *Handle) Status(ctx context.Context) ([]MigrationStatus, error) {
+	ctx, unlock, err := m.rlock(ctx, "Status")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	files, versions, err := m.readFilesAndGetVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return migrationStatus(files, versions), nil
+}
+
+func migrationStatus(files file.MigrationFiles, versions file.Versions) []MigrationStatus {
+	var latest file.Version
+	for _, v := range versions {
+		if v > latest {
+			latest = v
+		}
+	}
+
+	statuses := make([]MigrationStatus, 0, len(files))
+	onDisk := make(map[file.Version]bool, len(files))
+	for _, f := range files {
+		onDisk[f.Version] = true
+		s := MigrationStatus{
+			Version:     f.Version,
+			Applied:     versions.Contains(f.Version),
+			HasUpFile:   f.UpFile != nil,
+			HasDownFile: f.DownFile != nil,
+		}
+		if f.UpFile != nil {
+			s.Name = f.UpFile.Name
+		} else if f.DownFile != nil {
+			s.Name = f.DownFile.Name
+		}
+		s.Pending = !s.Applied && s.HasUpFile
+		s.OutOfOrder = s.Pending && f.Version < latest
+		statuses = append(statuses, s)
+	}
+	for _, v := range versions {
+		if !onDisk[v] {
+			statuses = append(statuses, MigrationStatus{
+				Version:  v,
+				Applied:  true,
+				Orphaned: true,
+			})
+		}
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses
+}
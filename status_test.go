@@ -0,0 +1,41 @@
+package migrate
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/db-journey/migrate/v2/file"
+)
+
+func TestStatus(t *testing.T) {
+	m, drv, cleanup := newMockHandle(t, map[string]string{
+		"001_first.up.sql":    "",
+		"001_first.down.sql":  "",
+		"002_second.up.sql":   "",
+		"003_third.up.sql":    "",
+		"003_third.down.sql":  "",
+		"004_fourth.down.sql": "",
+		"006_sixth.up.sql":    "",
+	})
+	defer cleanup()
+
+	drv.versions = file.Versions{5, 3, 1}
+
+	statuses, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []MigrationStatus{
+		{Version: 1, Name: "first", Applied: true, HasUpFile: true, HasDownFile: true},
+		{Version: 2, Name: "second", Pending: true, OutOfOrder: true, HasUpFile: true},
+		{Version: 3, Name: "third", Applied: true, HasUpFile: true, HasDownFile: true},
+		{Version: 4, Name: "fourth", HasDownFile: true},
+		{Version: 5, Applied: true, Orphaned: true},
+		{Version: 6, Name: "sixth", Pending: true, HasUpFile: true},
+	}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("Expected status:\n%+v\ngot:\n%+v", expected, statuses)
+	}
+}
@@ -0,0 +1,143 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"text/template"
+
+	"github.com/db-journey/migrate/v2/direction"
+	"github.com/db-journey/migrate/v2/driver"
+	"github.com/db-journey/migrate/v2/file"
+)
+
+// WithTemplateData sets data passed to migration templates (.tpl files)
+// instead of map of environment variables, e.g. map or struct
+// with schema name or tenant ID.
+func WithTemplateData(data interface{}) Option {
+	return func(h *Handle) error {
+		h.render.Data = data
+		return nil
+	}
+}
+
+// WithTemplateFuncs adds functions to migration templates,
+// overriding built in ones. In addition to functions listed
+// in file.RenderOptions, templates can use driver's quoting,
+// if driver implements driver.Quoter:
+//
+//	quoteIdent "name"    quoted identifier, e.g. "my schema" for postgres
+//	quoteLiteral "value" quoted string literal, e.g. 'it''s'
+func WithTemplateFuncs(funcs template.FuncMap) Option {
+	return func(h *Handle) error {
+		if h.render.Funcs == nil {
+			h.render.Funcs = template.FuncMap{}
+		}
+		for name, f := range funcs {
+			h.render.Funcs[name] = f
+		}
+		return nil
+	}
+}
+
+// WithPartials makes templates in dir available to all migration templates,
+// e.g. {{ template "grants" . }} executes grants.sql.tpl from dir.
+// Dir is read from the same file system as migrations, see file.RenderOptions.
+func WithPartials(dir string) Option {
+	return func(h *Handle) error {
+		if dir == "" {
+			return errors.New("partials directory can't be empty")
+		}
+		h.render.PartialsDir = dir
+		return nil
+	}
+}
+
+// WithPlaceholders enables substitution of ${name} placeholders
+// in all migration files with given values, independently of templates.
+// Unknown placeholder fails the whole run before any migration is applied,
+// $${ is written as literal ${.
+func WithPlaceholders(values map[string]string) Option {
+	return func(h *Handle) error {
+		h.render.Placeholders = make(map[string]string, len(values))
+		for name, value := range values {
+			h.render.Placeholders[name] = value
+		}
+		return nil
+	}
+}
+
+// checkPlaceholders checks placeholders of all files in plan before
+// the first of them is applied, so a typo doesn't leave the run half done.
+// Down files of up migrations are checked too in auto rollback mode.
+func (m *Handle) checkPlaceholders(files file.MigrationFiles, plan file.Files) error {
+	if m.render.Placeholders == nil {
+		return nil
+	}
+	for i := range plan {
+		if err := plan[i].CheckPlaceholders(); err != nil {
+			return err
+		}
+		if !m.autoRollback || plan[i].Direction != direction.Up {
+			continue
+		}
+		for _, f := range files {
+			if f.Version == plan[i].Version && f.DownFile != nil {
+				if err := f.DownFile.CheckPlaceholders(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkRunPlaceholders is checkPlaceholders for operations made of several runs,
+// plan returns files of all of them.
+func (m *Handle) checkRunPlaceholders(ctx context.Context, plan func(file.MigrationFiles, file.Versions) (file.Files, error)) error {
+	if m.render.Placeholders == nil {
+		return nil
+	}
+	files, versions, err := m.readFilesAndGetVersions(ctx)
+	if err != nil {
+		return err
+	}
+	planned, err := plan(files, versions)
+	if err != nil {
+		return err
+	}
+	return m.checkPlaceholders(files, planned)
+}
+
+// checkPartials makes partials read from migrations file system
+// and checks their directory exists.
+func (m *Handle) checkPartials() error {
+	if m.render.PartialsDir == "" {
+		return nil
+	}
+	m.render.PartialsFS = m.fsys
+	var err error
+	if m.fsys == nil {
+		_, err = os.Stat(m.render.PartialsDir)
+	} else {
+		_, err = fs.Stat(m.fsys, m.render.PartialsDir)
+	}
+	return err
+}
+
+// templateFuncs returns driver's quoting functions overridden by funcs.
+func templateFuncs(drv driver.Driver, funcs template.FuncMap) template.FuncMap {
+	all := template.FuncMap{
+		"quoteIdent": func(name string) (string, error) {
+			return driver.QuoteIdentifier(drv, name)
+		},
+		"quoteLiteral": func(value string) (string, error) {
+			return driver.QuoteLiteral(drv, value)
+		},
+	}
+	for name, f := range funcs {
+		all[name] = f
+	}
+	return all
+}
@@ -0,0 +1,125 @@
+package migrate
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"text/template"
+
+	"github.com/db-journey/migrate/v2/file"
+)
+
+// quotingMockDriver quotes identifiers with brackets.
+type quotingMockDriver struct {
+	mockDriver
+}
+
+func (d *quotingMockDriver) QuoteIdentifier(name string) string {
+	return "[" + name + "]"
+}
+
+func (d *quotingMockDriver) QuoteLiteral(value string) string {
+	return "'" + strings.Replace(value, "'", "''", -1) + "'"
+}
+
+func TestTemplateOptions(t *testing.T) {
+	files := map[string]string{
+		"001_tenant.up.sql.tpl": `CREATE TABLE {{ quoteIdent .Schema }}.t (name) VALUES ({{ quoteLiteral .Tenant | lower }});`,
+	}
+	m, _, cleanup := newMockHandle(t, files)
+	defer cleanup()
+
+	drv := &quotingMockDriver{}
+	opts := []Option{
+		WithTemplateData(map[string]string{"Schema": "tenant 1", "Tenant": "O'Brien"}),
+		WithTemplateFuncs(template.FuncMap{"lower": strings.ToLower}),
+	}
+	qm, err := New(drv, m.migrationsPath, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diags, err := qm.Validate(); err != nil || len(diags) != 0 {
+		t.Errorf("expected no diagnostics, got %v, %v", diags, err)
+	}
+	if err := qm.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expected := `CREATE TABLE [tenant 1].t (name) VALUES ('o''brien');`
+	if len(drv.migrated) != 1 || string(drv.migrated[0].Content) != expected {
+		t.Fatalf("expected %q, got %v", expected, drv.migrated)
+	}
+
+	// mockDriver doesn't implement driver.Quoter
+	m, err = New(&mockDriver{}, m.migrationsPath, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(context.Background()); err == nil || !strings.Contains(err.Error(), "can't quote identifiers") {
+		t.Errorf("expected quoting error, got %v", err)
+	}
+}
+
+func TestPartials(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/001_users.up.sql.tpl":  {Data: []byte(`CREATE TABLE users; {{ template "grants" "users" }}`)},
+		"migrations/002_orders.up.sql.tpl": {Data: []byte(`CREATE TABLE orders; {{ template "grants" "orders" }}`)},
+		"partials/grants.sql.tpl":          {Data: []byte(`GRANT SELECT ON {{ . }} TO reader;`)},
+	}
+	drv := &mockDriver{}
+	m, err := New(drv, "migrations", WithFS(fsys), WithPartials("partials"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(drv.migrated) != 2 || string(drv.migrated[1].Content) != "CREATE TABLE orders; GRANT SELECT ON orders TO reader;" {
+		t.Fatalf("unexpected migrations %v", drv.migrated)
+	}
+
+	if _, err := New(drv, "migrations", WithFS(fsys), WithPartials("missing")); err == nil {
+		t.Error("expected error for missing partials directory")
+	}
+}
+
+func TestPlaceholders(t *testing.T) {
+	m, drv, cleanup := newMockHandle(t, map[string]string{
+		"001_users.up.sql":   `CREATE TABLE ${schema}.users (doc jsonb DEFAULT '{"tpl": "{{ x }}", "s": "$${ignored}"}');`,
+		"001_users.down.sql": `DROP TABLE ${schema}.users;`,
+		"002_orders.up.sql":  `CREATE TABLE ${schema}.orders OWNER ${owner};`,
+	}, WithPlaceholders(map[string]string{"schema": "tenant_1"}))
+	defer cleanup()
+
+	err := m.Up(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "002_orders.up.sql: line 1: unknown placeholder ${owner}") {
+		t.Fatalf("expected unknown placeholder error, got %v", err)
+	}
+	if len(drv.migrated) != 0 {
+		t.Fatalf("expected nothing migrated, got %v", drv.migrated)
+	}
+
+	diags, err := m.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diags) != 1 || diags[0].Kind != file.PlaceholderError || diags[0].Version != 2 {
+		t.Fatalf("expected placeholder error of version 2, got %v", diags)
+	}
+
+	if err := m.ApplyVersion(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	expected := `CREATE TABLE tenant_1.users (doc jsonb DEFAULT '{"tpl": "{{ x }}", "s": "${ignored}"}');`
+	if len(drv.migrated) != 1 || string(drv.migrated[0].Content) != expected {
+		t.Fatalf("expected %q, got %v", expected, drv.migrated)
+	}
+
+	// reset doesn't roll back anything when up files can't be applied
+	if err := m.Reset(context.Background()); err == nil || !strings.Contains(err.Error(), "${owner}") {
+		t.Fatalf("expected unknown placeholder error, got %v", err)
+	}
+	if len(drv.migrated) != 1 {
+		t.Fatalf("expected nothing more migrated, got %v", drv.migrated)
+	}
+}
@@ -0,0 +1,101 @@
+package migrate
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/db-journey/migrate/v2/driver"
+	"github.com/db-journey/migrate/v2/file"
+)
+
+// txMockDriver is mockDriver which runs migrations in transactions.
+type txMockDriver struct {
+	mockDriver
+	commits int
+}
+
+func (d *txMockDriver) BeginTx(ctx context.Context) (driver.Tx, error) {
+	versions, _ := d.Versions()
+	return &mockTx{d: d, staged: &mockDriver{versions: versions}}, nil
+}
+
+// mockTx applies migrations to staged mockDriver until committed.
+type mockTx struct {
+	d      *txMockDriver
+	staged *mockDriver
+}
+
+func (tx *mockTx) MigrateContext(ctx context.Context, f file.File) error {
+	return tx.staged.MigrateContext(ctx, f)
+}
+
+func (tx *mockTx) VersionsContext(ctx context.Context) (file.Versions, error) {
+	return tx.staged.Versions()
+}
+
+func (tx *mockTx) Commit() error {
+	tx.d.mu.Lock()
+	defer tx.d.mu.Unlock()
+	tx.d.versions = tx.staged.versions
+	tx.d.migrated = append(tx.d.migrated, tx.staged.migrated...)
+	tx.d.commits++
+	return nil
+}
+
+func (tx *mockTx) Rollback() error {
+	return nil
+}
+
+func TestSingleTransaction(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("/tmp", "migrate-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	for name, content := range map[string]string{
+		"001_first.up.sql":    "CREATE TABLE first;",
+		"001_first.down.sql":  "DROP TABLE first;",
+		"002_second.up.sql":   "CREATE TABLE second;",
+		"002_second.down.sql": "DROP TABLE second;",
+		"003_third.up.sql":    "FAIL",
+	} {
+		if err := ioutil.WriteFile(path.Join(tmpdir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := New(&mockDriver{}, tmpdir, WithSingleTransaction()); err == nil {
+		t.Error("Expected driver without transactions to be rejected")
+	}
+
+	drv := &txMockDriver{}
+	m, err := New(drv, tmpdir, WithSingleTransaction())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Up(ctx); err == nil {
+		t.Fatal("Expected migration to fail")
+	}
+	if len(drv.versions) != 0 || drv.commits != 0 {
+		t.Fatalf("Expected all migrations to be rolled back, got versions %v", drv.versions)
+	}
+
+	if err := m.MigrateTo(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Redo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(drv.versions, file.Versions{2, 1}) {
+		t.Errorf("Expected versions %v, got %v", file.Versions{2, 1}, drv.versions)
+	}
+	if drv.commits != 2 {
+		t.Errorf("Expected single commit per operation, got %d commits", drv.commits)
+	}
+}
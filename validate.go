@@ -0,0 +1,52 @@
+package migrate
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/db-journey/migrate/v2/driver"
+	"github.com/db-journey/migrate/v2/file"
+)
+
+// Validate statically checks migration files and returns all problems found.
+// It doesn't touch the database, see file.Validate.
+// With extra directories it also reports versions defined in several of them.
+func (m *Handle) Validate() ([]file.Diagnostic, error) {
+	var diags []file.Diagnostic
+	defined := map[file.Version][]string{}
+	for _, dir := range m.dirs() {
+		dirDiags, err := file.ValidateWith(dir, file.FilenameRegex(driver.FileExtension(m.drv)), m.fileOptions())
+		if err != nil {
+			return nil, err
+		}
+		diags = append(diags, dirDiags...)
+
+		files, err := m.readDir(dir)
+		if err != nil {
+			// duplicates within directory are already reported
+			continue
+		}
+		for _, mf := range files {
+			f := mf.UpFile
+			if f == nil {
+				f = mf.DownFile
+			}
+			defined[mf.Version] = append(defined[mf.Version], path.Join(f.Path, f.FileName))
+		}
+	}
+	for version, paths := range defined {
+		if len(paths) > 1 {
+			diags = append(diags, file.Diagnostic{
+				Kind:      file.DuplicateVersion,
+				Version:   version,
+				FileNames: paths,
+				Message:   fmt.Sprintf("version %d is defined in %d directories", version, len(paths)),
+			})
+		}
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		return diags[i].Version < diags[j].Version
+	})
+	return diags, nil
+}
@@ -0,0 +1,27 @@
+package migrate
+
+import (
+	"testing"
+
+	"github.com/db-journey/migrate/v2/file"
+)
+
+func TestValidate(t *testing.T) {
+	m, drv, cleanup := newMockHandle(t, map[string]string{
+		"001_first.up.sql":    "CREATE TABLE first;",
+		"001_first.down.sql":  "DROP TABLE first;",
+		"002_second.down.sql": "DROP TABLE second;",
+	})
+	defer cleanup()
+
+	diags, err := m.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diags) != 1 || diags[0].Kind != file.MissingUpFile || diags[0].Version != 2 {
+		t.Fatalf("expected missing up file of version 2, got %v", diags)
+	}
+	if len(drv.migrated) != 0 {
+		t.Fatalf("expected no migrations, got %v", drv.migrated)
+	}
+}
@@ -0,0 +1,112 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/db-journey/migrate/v2/direction"
+	"github.com/db-journey/migrate/v2/file"
+)
+
+// Drift describes applied migration whose up file has changed
+// since it was applied.
+type Drift struct {
+	Version  file.Version
+	FileName string
+
+	// Recorded is the checksum stored in the version table.
+	Recorded string
+
+	// Actual is the checksum of the up file as it's rendered now.
+	Actual string
+}
+
+// WithDriftCheck makes operations applying up migrations refuse
+// to run while Verify reports any drift.
+func WithDriftCheck() Option {
+	return func(h *Handle) error {
+		h.driftCheck = true
+		return nil
+	}
+}
+
+// Verify compares checksums recorded for applied migrations with their
+// up files and returns every mismatch. Versions with no recorded checksum
+// or no up file on disk are skipped.
+// Driver must implement driver.Recorder.
+func (m *Handle) Verify(ctx context.Context) ([]Drift, error) {
+	ctx, unlock, err := m.rlock(ctx, "Verify")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	files, err := m.readFiles()
+	if err != nil {
+		return nil, err
+	}
+	return m.verify(files)
+}
+
+func (m *Handle) verify(files file.MigrationFiles) ([]Drift, error) {
+	records, err := m.records()
+	if err != nil {
+		return nil, err
+	}
+
+	upFiles := make(map[file.Version]*file.File, len(files))
+	for _, f := range files {
+		if f.UpFile != nil {
+			upFiles[f.Version] = f.UpFile
+		}
+	}
+
+	var drifts []Drift
+	for _, record := range records {
+		upFile, ok := upFiles[record.Version]
+		if record.Checksum == "" || !ok {
+			continue
+		}
+		if err := upFile.ReadContent(); err != nil {
+			return nil, err
+		}
+		if actual := upFile.Checksum(); actual != record.Checksum {
+			drifts = append(drifts, Drift{
+				Version:  record.Version,
+				FileName: upFile.FileName,
+				Recorded: record.Checksum,
+				Actual:   actual,
+			})
+		}
+	}
+	return drifts, nil
+}
+
+// checkDrift returns error if plan applies up migrations
+// while any of applied migrations has drifted.
+func (m *Handle) checkDrift(files file.MigrationFiles, plan file.Files) error {
+	if !m.driftCheck || !hasUpFiles(plan) {
+		return nil
+	}
+	drifts, err := m.verify(files)
+	if err != nil {
+		return err
+	}
+	if len(drifts) == 0 {
+		return nil
+	}
+	names := make([]string, len(drifts))
+	for i, d := range drifts {
+		names[i] = fmt.Sprintf("%d (%s)", d.Version, d.FileName)
+	}
+	return fmt.Errorf("refusing to apply migrations, files of applied migrations were modified: %s", strings.Join(names, ", "))
+}
+
+func hasUpFiles(plan file.Files) bool {
+	for _, f := range plan {
+		if f.Direction == direction.Up {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package migrate
+
+import (
+	"context"
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	m, _, cleanup := newMockHandle(t, map[string]string{
+		"001_first.up.sql":   "CREATE TABLE first;",
+		"001_first.down.sql": "DROP TABLE first;",
+		"002_second.up.sql":  "CREATE TABLE second;",
+	}, WithDriftCheck())
+	defer cleanup()
+
+	if err := m.Migrate(ctx, +1); err != nil {
+		t.Fatal(err)
+	}
+	drifts, err := m.Verify(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drifts) != 0 {
+		t.Fatalf("Expected no drift, got %+v", drifts)
+	}
+
+	err = ioutil.WriteFile(path.Join(m.migrationsPath, "001_first.up.sql"), []byte("CREATE TABLE first_modified;"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	drifts, err = m.Verify(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drifts) != 1 || drifts[0].Version != 1 || drifts[0].FileName != "001_first.up.sql" {
+		t.Fatalf("Expected drift of version 1, got %+v", drifts)
+	}
+	if drifts[0].Recorded == drifts[0].Actual {
+		t.Errorf("Expected different checksums, got %+v", drifts[0])
+	}
+
+	for op, migrate := range map[string]func() error{
+		"Up":           func() error { return m.Up(ctx) },
+		"Migrate":      func() error { return m.Migrate(ctx, +1) },
+		"MigrateTo":    func() error { return m.MigrateTo(ctx, 2) },
+		"ApplyVersion": func() error { return m.ApplyVersion(ctx, 2) },
+	} {
+		if err := migrate(); err == nil {
+			t.Fatalf("Expected %s to refuse to run while drift exists", op)
+		}
+	}
+	version, err := m.Version(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 1 {
+		t.Errorf("Expected version 1, got %d", version)
+	}
+
+	// drifted migration can still be rolled back
+	if err := m.Migrate(ctx, -1); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,81 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/db-journey/migrate/v2/file"
+)
+
+// VersionGenerator returns version for migration created by Create
+// at time now, given versions of existing migrations in ascending order.
+// Version is returned as it's written to file names, e.g. zero padded.
+type VersionGenerator func(now time.Time, existing file.Versions) (string, error)
+
+// TimestampVersion generates versions from UTC time, e.g. 20060102150405.
+// It's the default. Create refuses the version if a migration
+// with the same or newer timestamp already exists.
+func TimestampVersion(now time.Time, _ file.Versions) (string, error) {
+	return now.UTC().Format("20060102150405"), nil
+}
+
+// SequentialVersion generates versions incrementing the latest existing one,
+// zero padded to width digits, e.g. 0001, 0002 for width 4.
+func SequentialVersion(width int) VersionGenerator {
+	return func(_ time.Time, existing file.Versions) (string, error) {
+		version := file.Version(1)
+		if len(existing) > 0 {
+			version = existing[len(existing)-1] + 1
+		}
+		return fmt.Sprintf("%0*d", width, version), nil
+	}
+}
+
+// WithVersionGenerator sets how Create numbers new migrations.
+func WithVersionGenerator(g VersionGenerator) Option {
+	return func(h *Handle) error {
+		if g == nil {
+			return errors.New("version generator can't be nil")
+		}
+		h.versionGenerator = g
+		return nil
+	}
+}
+
+// WithClock replaces time.Now as source of current time for Create.
+func WithClock(now func() time.Time) Option {
+	return func(h *Handle) error {
+		if now == nil {
+			return errors.New("clock can't be nil")
+		}
+		h.now = now
+		return nil
+	}
+}
+
+// nextVersion generates version for a new migration, refusing
+// to reuse version of existing one or to create it out of order.
+func (m *Handle) nextVersion(files file.MigrationFiles) (file.Version, string, error) {
+	existing := make(file.Versions, 0, len(files))
+	for _, f := range files {
+		existing = append(existing, f.Version)
+	}
+	versionStr, err := m.versionGenerator(m.now(), existing)
+	if err != nil {
+		return 0, "", err
+	}
+	v, err := strconv.ParseUint(versionStr, 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid version %q generated: %w", versionStr, err)
+	}
+	version := file.Version(v)
+	if existing.Contains(version) {
+		return 0, "", fmt.Errorf("can't create migration: version %d already exists", version)
+	}
+	if len(existing) > 0 && existing[len(existing)-1] > version {
+		return 0, "", fmt.Errorf("can't create migration: version %d is older than the latest version %d", version, existing[len(existing)-1])
+	}
+	return version, versionStr, nil
+}
@@ -0,0 +1,134 @@
+package migrate
+
+import (
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/db-journey/migrate/v2/file"
+)
+
+func TestTimestampVersion(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 30, 0, 0, time.FixedZone("CET", 3600))
+	tests := []struct {
+		existing file.Versions
+		expected string
+	}{
+		{nil, "20240301113000"},
+		{file.Versions{20240101000000}, "20240301113000"},
+		{file.Versions{20240301113000}, "20240301113000"},
+		{file.Versions{1, 20250101000000}, "20240301113000"},
+	}
+	for _, test := range tests {
+		version, err := TimestampVersion(now, test.existing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != test.expected {
+			t.Errorf("expected %s for %v, got %s", test.expected, test.existing, version)
+		}
+	}
+}
+
+func TestCreateTimestampCollision(t *testing.T) {
+	now := time.Date(2024, 3, 1, 11, 30, 59, 0, time.UTC)
+	m, _, cleanup := newMockHandle(t, nil, WithClock(func() time.Time { return now }))
+	defer cleanup()
+
+	mf, err := m.Create("first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mf.Version != 20240301113059 {
+		t.Errorf("expected version from clock, got %d", mf.Version)
+	}
+	// same second
+	if _, err := m.Create("second"); err == nil {
+		t.Error("expected Create to refuse existing timestamp")
+	}
+	// clock went back
+	now = now.Add(-time.Minute)
+	if _, err := m.Create("third"); err == nil {
+		t.Error("expected Create to refuse timestamp older than the latest version")
+	}
+	if names := dirNames(t, m.migrationsPath); len(names) != 2 {
+		t.Errorf("expected only files of the first migration, got %v", names)
+	}
+	now = now.Add(2 * time.Minute)
+	if mf, err = m.Create("fourth"); err != nil {
+		t.Fatal(err)
+	}
+	if mf.Version != 20240301113159 {
+		t.Errorf("expected version from clock, got %d", mf.Version)
+	}
+}
+
+func TestCreateSequential(t *testing.T) {
+	m, _, cleanup := newMockHandle(t, map[string]string{
+		"0001_first.up.sql": "",
+	}, WithVersionGenerator(SequentialVersion(4)))
+	defer cleanup()
+
+	mf, err := m.Create("second one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mf.Version != 2 || mf.UpFile.FileName != "0002_second_one.up.sql" {
+		t.Errorf("unexpected version %d, file %s", mf.Version, mf.UpFile.FileName)
+	}
+	if _, err := m.Create("third"); err != nil {
+		t.Fatal(err)
+	}
+	names := dirNames(t, m.migrationsPath)
+	expected := []string{
+		"0001_first.up.sql",
+		"0002_second_one.down.sql", "0002_second_one.up.sql",
+		"0003_third.down.sql", "0003_third.up.sql",
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
+
+func TestCreateCollision(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	fixed := func(now time.Time, _ file.Versions) (string, error) {
+		return now.Format("200601021504"), nil
+	}
+	m, _, cleanup := newMockHandle(t, nil, WithClock(func() time.Time { return now }), WithVersionGenerator(fixed))
+	defer cleanup()
+
+	mf, err := m.Create("first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mf.Version != 202403011230 {
+		t.Errorf("expected version from clock, got %d", mf.Version)
+	}
+	if _, err := m.Create("second"); err == nil {
+		t.Error("expected Create to refuse existing version")
+	}
+	if names := dirNames(t, m.migrationsPath); len(names) != 2 {
+		t.Errorf("expected only files of the first migration, got %v", names)
+	}
+
+	m.versionGenerator = func(time.Time, file.Versions) (string, error) { return "v1", nil }
+	if _, err := m.Create("third"); err == nil {
+		t.Error("expected Create to refuse invalid version")
+	}
+}
+
+func dirNames(t *testing.T, dir string) []string {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	return names
+}